@@ -1,6 +1,19 @@
 package admin
 
+import (
+	"context"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
 type AdminServiceInterface interface {
-	UpdatePersonalDeduction(amount float64) error
-	UpdateKReceiptDeductionMax(amount float64) error
+	GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error)
+	UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error)
+	CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error
+	UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error)
 }
@@ -1,8 +1,11 @@
 package admin
 
 import (
+	"context"
 	"errors"
 
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
 	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/repository"
 )
 
@@ -16,16 +19,93 @@ func NewAdminService(taxRepo repository.TaxDeductionConfigRepositoryInterface) A
 	}
 }
 
-func (s *adminService) UpdatePersonalDeduction(amount float64) error {
+func (s *adminService) GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error) {
+	return s.taxRepo.GetConfig(ctx)
+}
+
+func (s *adminService) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
 	if amount < 10000 || amount > 100000 {
-		return errors.New("amount must be between 10,000 and 100,000")
+		return 0, errors.New("amount must be between 10,000 and 100,000")
+	}
+	newVersion, err := s.taxRepo.UpdatePersonalDeduction(ctx, amount, version)
+	metrics.AdminUpdates.WithLabelValues("personal", metrics.UpdateResult(err)).Inc()
+	return newVersion, err
+}
+
+func (s *adminService) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	if amount < 0 || amount > 100000 {
+		return 0, errors.New("amount must be less than or equal to 100,000")
 	}
-	return s.taxRepo.UpdatePersonalDeduction(amount)
+	newVersion, err := s.taxRepo.UpdateKReceiptDeductionMax(ctx, amount, version)
+	metrics.AdminUpdates.WithLabelValues("k-receipt", metrics.UpdateResult(err)).Inc()
+	return newVersion, err
 }
 
-func (s *adminService) UpdateKReceiptDeductionMax(amount float64) error {
+func (s *adminService) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
 	if amount < 0 || amount > 100000 {
-		return errors.New("amount must be less than or equal to 100,000")
+		return 0, errors.New("amount must be less than or equal to 100,000")
+	}
+	newVersion, err := s.taxRepo.UpdateInsuranceDeductionMax(ctx, amount, version)
+	metrics.AdminUpdates.WithLabelValues("insurance", metrics.UpdateResult(err)).Inc()
+	return newVersion, err
+}
+
+func (s *adminService) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	if amount < 0 || amount > 500000 {
+		return 0, errors.New("amount must be less than or equal to 500,000")
+	}
+	newVersion, err := s.taxRepo.UpdateRMFDeductionMax(ctx, amount, version)
+	metrics.AdminUpdates.WithLabelValues("rmf", metrics.UpdateResult(err)).Inc()
+	return newVersion, err
+}
+
+func (s *adminService) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	if amount < 0 || amount > 200000 {
+		return 0, errors.New("amount must be less than or equal to 200,000")
+	}
+	newVersion, err := s.taxRepo.UpdateSSFDeductionMax(ctx, amount, version)
+	metrics.AdminUpdates.WithLabelValues("ssf", metrics.UpdateResult(err)).Inc()
+	return newVersion, err
+}
+
+func (s *adminService) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	return s.taxRepo.GetTaxYearConfigs(ctx)
+}
+
+func (s *adminService) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	if err := checkTaxYearConfig(config); err != nil {
+		return err
+	}
+	err := s.taxRepo.CreateTaxYearConfig(ctx, config)
+	metrics.AdminUpdates.WithLabelValues("tax-year", metrics.UpdateResult(err)).Inc()
+	return err
+}
+
+func (s *adminService) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	if err := checkTaxYearConfig(config); err != nil {
+		return 0, err
+	}
+	newVersion, err := s.taxRepo.UpdateTaxYearConfig(ctx, config, version)
+	metrics.AdminUpdates.WithLabelValues("tax-year", metrics.UpdateResult(err)).Inc()
+	return newVersion, err
+}
+
+// checkTaxYearConfig applies the same limits as the single-field updates of
+// MainConfig.
+func checkTaxYearConfig(config *domains.TaxDeductionConfig) error {
+	switch {
+	case config.TaxYear == nil:
+		return errors.New("tax year is required")
+	case config.PersonalDeduction < 10000 || config.PersonalDeduction > 100000:
+		return errors.New("personal deduction must be between 10,000 and 100,000")
+	case config.KReceiptDeductionMax < 0 || config.KReceiptDeductionMax > 100000:
+		return errors.New("k-receipt limit must be less than or equal to 100,000")
+	case config.InsuranceDeductionMax < 0 || config.InsuranceDeductionMax > 100000:
+		return errors.New("insurance limit must be less than or equal to 100,000")
+	case config.RMFDeductionMax < 0 || config.RMFDeductionMax > 500000:
+		return errors.New("rmf limit must be less than or equal to 500,000")
+	case config.SSFDeductionMax < 0 || config.SSFDeductionMax > 200000:
+		return errors.New("ssf limit must be less than or equal to 200,000")
 	}
-	return s.taxRepo.UpdateKReceiptDeductionMax(amount)
+	return nil
 }
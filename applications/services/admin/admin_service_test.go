@@ -1,17 +1,20 @@
 package admin
 
 import (
+	"context"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
 )
 type MockTaxDeductionConfigRepository struct {
 	mock.Mock
 }
 
-func (m *MockTaxDeductionConfigRepository) GetConfig() (*domains.TaxDeductionConfig, error) {
+func (m *MockTaxDeductionConfigRepository) GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error) {
 	args := m.Called()
 	if config, ok := args.Get(0).(*domains.TaxDeductionConfig); ok {
 		return config, args.Error(1)
@@ -19,33 +22,67 @@ func (m *MockTaxDeductionConfigRepository) GetConfig() (*domains.TaxDeductionCon
 	return nil, args.Error(1)
 }
 
-func (m *MockTaxDeductionConfigRepository) UpdatePersonalDeduction(amount float64) error {
-	args := m.Called(amount)
-	return args.Error(0)
+func (m *MockTaxDeductionConfigRepository) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	args := m.Called()
+	if configs, ok := args.Get(0).([]domains.TaxDeductionConfig); ok {
+		return configs, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockTaxDeductionConfigRepository) UpdateKReceiptDeductionMax(amount float64) error {
-	args := m.Called(amount)
+func (m *MockTaxDeductionConfigRepository) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	args := m.Called(config)
 	return args.Error(0)
 }
 
+func (m *MockTaxDeductionConfigRepository) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	args := m.Called(config, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // Testing the AdminService with mocks
 func TestAdminService_UpdatePersonalDeduction(t *testing.T) {
 	mockRepo := new(MockTaxDeductionConfigRepository)
 	adminService := NewAdminService(mockRepo)
 
 	// Test updating with a valid amount
-	mockRepo.On("UpdatePersonalDeduction", 70000.0).Return(nil)
-	err := adminService.UpdatePersonalDeduction(70000.0)
+	mockRepo.On("UpdatePersonalDeduction", 70000.0, int64(3)).Return(int64(4), nil)
+	version, err := adminService.UpdatePersonalDeduction(context.Background(), 70000.0, 3)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(4), version)
 	mockRepo.AssertExpectations(t)
 
 	// Test updating with an invalid amount (too low)
-	err = adminService.UpdatePersonalDeduction(9000)
+	_, err = adminService.UpdatePersonalDeduction(context.Background(), 9000, 3)
 	assert.Error(t, err, "amount must be between 10,000 and 100,000")
 
 	// Test updating with an invalid amount (too high)
-	err = adminService.UpdatePersonalDeduction(101000)
+	_, err = adminService.UpdatePersonalDeduction(context.Background(), 101000, 3)
 	assert.Error(t, err, "amount must be between 10,000 and 100,000")
 }
 
@@ -54,16 +91,111 @@ func TestAdminService_UpdateKReceiptDeductionMax(t *testing.T) {
 	adminService := NewAdminService(mockRepo)
 
 	// Test updating within valid range
-	mockRepo.On("UpdateKReceiptDeductionMax", 50000.0).Return(nil)
-	err := adminService.UpdateKReceiptDeductionMax(50000.0)
+	mockRepo.On("UpdateKReceiptDeductionMax", 50000.0, int64(1)).Return(int64(2), nil)
+	version, err := adminService.UpdateKReceiptDeductionMax(context.Background(), 50000.0, 1)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
 	mockRepo.AssertExpectations(t)
 
 	// Test updating with a negative amount
-	err = adminService.UpdateKReceiptDeductionMax(-100.0)
+	_, err = adminService.UpdateKReceiptDeductionMax(context.Background(), -100.0, 1)
 	assert.Error(t, err, "amount must be less than or equal to 100,000")
 
 	// Test updating with an amount too high
-	err = adminService.UpdateKReceiptDeductionMax(100001.0)
+	_, err = adminService.UpdateKReceiptDeductionMax(context.Background(), 100001.0, 1)
 	assert.Error(t, err, "amount must be less than or equal to 100,000")
-}
\ No newline at end of file
+}
+
+func TestAdminService_UpdateAllowanceCaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		label  string
+		max    float64
+		update func(AdminServiceInterface, float64) (int64, error)
+	}{
+		{"Insurance", "UpdateInsuranceDeductionMax", "insurance", 100000, func(s AdminServiceInterface, amount float64) (int64, error) {
+			return s.UpdateInsuranceDeductionMax(context.Background(), amount, 1)
+		}},
+		{"RMF", "UpdateRMFDeductionMax", "rmf", 500000, func(s AdminServiceInterface, amount float64) (int64, error) {
+			return s.UpdateRMFDeductionMax(context.Background(), amount, 1)
+		}},
+		{"SSF", "UpdateSSFDeductionMax", "ssf", 200000, func(s AdminServiceInterface, amount float64) (int64, error) {
+			return s.UpdateSSFDeductionMax(context.Background(), amount, 1)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTaxDeductionConfigRepository)
+			adminService := NewAdminService(mockRepo)
+			successes := metrics.AdminUpdates.WithLabelValues(tt.label, metrics.ResultSuccess)
+			before := testutil.ToFloat64(successes)
+
+			mockRepo.On(tt.method, tt.max, int64(1)).Return(int64(2), nil)
+			version, err := tt.update(adminService, tt.max)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), version)
+			assert.Equal(t, before+1, testutil.ToFloat64(successes))
+			mockRepo.AssertExpectations(t)
+
+			_, err = tt.update(adminService, -1)
+			assert.Error(t, err)
+			_, err = tt.update(adminService, tt.max+1)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAdminService_TaxYearConfigs(t *testing.T) {
+	mockRepo := new(MockTaxDeductionConfigRepository)
+	adminService := NewAdminService(mockRepo)
+	successes := metrics.AdminUpdates.WithLabelValues("tax-year", metrics.ResultSuccess)
+	before := testutil.ToFloat64(successes)
+
+	year := 2025
+	config := domains.DefaultTaxDeductionConfig()
+	config.TaxYear = &year
+
+	mockRepo.On("CreateTaxYearConfig", &config).Return(nil)
+	assert.NoError(t, adminService.CreateTaxYearConfig(context.Background(), &config))
+	mockRepo.On("UpdateTaxYearConfig", &config, int64(1)).Return(int64(2), nil)
+	version, err := adminService.UpdateTaxYearConfig(context.Background(), &config, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+	assert.Equal(t, before+2, testutil.ToFloat64(successes))
+	mockRepo.AssertExpectations(t)
+
+	// Limits out of range never reach the repository.
+	invalid := config
+	invalid.RMFDeductionMax = 500001
+	assert.Error(t, adminService.CreateTaxYearConfig(context.Background(), &invalid))
+	_, err = adminService.UpdateTaxYearConfig(context.Background(), &invalid, 1)
+	assert.Error(t, err)
+	mockRepo.AssertNumberOfCalls(t, "CreateTaxYearConfig", 1)
+	mockRepo.AssertNumberOfCalls(t, "UpdateTaxYearConfig", 1)
+}
+
+func TestAdminService_UpdatePersonalDeduction_VersionConflict(t *testing.T) {
+	mockRepo := new(MockTaxDeductionConfigRepository)
+	adminService := NewAdminService(mockRepo)
+
+	conflicts := metrics.AdminUpdates.WithLabelValues("personal", metrics.ResultConflict)
+	before := testutil.ToFloat64(conflicts)
+
+	mockRepo.On("UpdatePersonalDeduction", 70000.0, int64(1)).Return(int64(0), domains.ErrConfigVersionConflict)
+	_, err := adminService.UpdatePersonalDeduction(context.Background(), 70000.0, 1)
+	assert.ErrorIs(t, err, domains.ErrConfigVersionConflict)
+	assert.Equal(t, before+1, testutil.ToFloat64(conflicts))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAdminService_GetConfig(t *testing.T) {
+	mockRepo := new(MockTaxDeductionConfigRepository)
+	adminService := NewAdminService(mockRepo)
+
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{ConfigName: "MainConfig", PersonalDeduction: 60000, Version: 5}, nil)
+	config, err := adminService.GetConfig(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), config.Version)
+}
@@ -1,10 +1,14 @@
 package tax
 
 import (
+	"context"
 	"math"
 
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
 	"github.com/thitiphum-bluesage/assessment-tax/utilities"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 )
 
 func getTaxBrackets() []struct {
@@ -44,15 +48,40 @@ func calculateProgressiveTax(income float64) float64 {
 	return tax
 }
 
-func calculateProgressiveTaxWithDetails(income float64) ([]schemas.TaxLevel, float64) {
+// taxLevelPrinter returns the printer for tax level labels. Without a language
+// requested by the client, the labels keep the Thai wording of the original
+// API specification, e.g. "2,000,001 ขึ้นไป".
+func taxLevelPrinter(ctx context.Context) *message.Printer {
+	locale := i18n.Locale(ctx)
+	if locale == language.Und {
+		locale = language.Thai
+	}
+	return i18n.NewPrinter(locale)
+}
+
+// taxLevelLabels names every bracket by its income range, e.g. "150,001-500,000",
+// formatting the numbers for the printer's locale.
+func taxLevelLabels(printer *message.Printer) []string {
+	brackets := getTaxBrackets()
+	labels := make([]string, len(brackets))
+	lowerBound := 0.0
+	for i, bracket := range brackets {
+		if bracket.UpperBound == math.MaxFloat64 {
+			labels[i] = printer.Sprintf("%d and above", int64(lowerBound))
+		} else {
+			labels[i] = printer.Sprintf("%d-%d", int64(lowerBound), int64(bracket.UpperBound))
+		}
+		lowerBound = bracket.UpperBound + 1
+	}
+	return labels
+}
+
+func calculateProgressiveTaxWithDetails(income float64, printer *message.Printer) ([]schemas.TaxLevel, float64) {
 	brackets := getTaxBrackets()
 
-	detailResponse := []schemas.TaxLevel{
-		{Level: "0-150,000", Tax: 0.0},
-		{Level: "150,001-500,000", Tax: 0.0},
-		{Level: "500,001-1,000,000", Tax: 0.0},
-		{Level: "1,000,001-2,000,000", Tax: 0.0},
-		{Level: "2,000,001 ขึ้นไป", Tax: 0.0},
+	detailResponse := make([]schemas.TaxLevel, len(brackets))
+	for i, label := range taxLevelLabels(printer) {
+		detailResponse[i] = schemas.TaxLevel{Level: label, Tax: 0.0}
 	}
 
 	tax := 0.0
@@ -76,3 +105,91 @@ func calculateProgressiveTaxWithDetails(income float64) ([]schemas.TaxLevel, flo
 
 	return detailResponse, tax
 }
+
+// calculateProgressiveTaxBrackets is calculateProgressiveTaxWithDetails with
+// the bounds and rate of every bracket.
+func calculateProgressiveTaxBrackets(income float64, printer *message.Printer) ([]schemas.TaxBracket, float64) {
+	taxLevels, tax := calculateProgressiveTaxWithDetails(income, printer)
+
+	brackets := make([]schemas.TaxBracket, len(taxLevels))
+	lowerBound := 0.0
+	for i, bracket := range getTaxBrackets() {
+		brackets[i] = schemas.TaxBracket{
+			Level:      taxLevels[i].Level,
+			LowerBound: lowerBound,
+			Rate:       bracket.TaxRate,
+			Tax:        taxLevels[i].Tax,
+		}
+		if bracket.UpperBound != math.MaxFloat64 {
+			upperBound := bracket.UpperBound
+			brackets[i].UpperBound = &upperBound
+		}
+		lowerBound = bracket.UpperBound
+	}
+	return brackets, tax
+}
+
+// marginalBracket returns the rate of the bracket that taxable income lands
+// in and how many baht of taxable income are left before the next bracket's
+// rate applies. The distance is nil in the top bracket.
+func marginalBracket(income float64) (float64, *float64) {
+	brackets := getTaxBrackets()
+	for _, bracket := range brackets {
+		if income > bracket.UpperBound {
+			continue
+		}
+		if bracket.UpperBound == math.MaxFloat64 {
+			return bracket.TaxRate, nil
+		}
+		distance := utilities.FormatToTwoDecimals(bracket.UpperBound - income)
+		return bracket.TaxRate, &distance
+	}
+	return brackets[len(brackets)-1].TaxRate, nil
+}
+
+// effectiveRate is tax as a share of gross income, rounded to four decimal
+// places, e.g. 0.058 for 5.8%.
+func effectiveRate(tax, grossIncome float64) float64 {
+	if grossIncome <= 0 {
+		return 0
+	}
+	return math.Round(tax/grossIncome*10000) / 10000
+}
+
+// grossIncomeForNet inverts income - calculateProgressiveTax(income - deductions):
+// it returns the gross income that leaves net after tax. Within a bracket every
+// extra baht of gross keeps (1 - rate) baht, so the bracket net income lands in
+// is found by comparing it with the net income at each upper bound.
+func grossIncomeForNet(net, deductions float64) float64 {
+	if net <= deductions {
+		return net
+	}
+
+	brackets := getTaxBrackets()
+	taxBelow := 0.0
+	previousUpperBound := 0.0
+	for _, bracket := range brackets {
+		bracketTax := utilities.FormatToTwoDecimals((bracket.UpperBound - previousUpperBound) * bracket.TaxRate)
+		if bracket.UpperBound != math.MaxFloat64 && net > deductions+bracket.UpperBound-taxBelow-bracketTax {
+			taxBelow += bracketTax
+			previousUpperBound = bracket.UpperBound
+			continue
+		}
+		taxableIncome := previousUpperBound + (net-deductions-previousUpperBound+taxBelow)/(1-bracket.TaxRate)
+		return utilities.FormatToTwoDecimals(deductions + taxableIncome)
+	}
+	return net
+}
+
+// taxFreeIncome returns the taxable income up to which no tax is due, i.e.
+// the upper bound of the 0% brackets at the bottom of the scale.
+func taxFreeIncome() float64 {
+	taxFree := 0.0
+	for _, bracket := range getTaxBrackets() {
+		if bracket.TaxRate > 0 {
+			break
+		}
+		taxFree = bracket.UpperBound
+	}
+	return taxFree
+}
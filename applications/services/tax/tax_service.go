@@ -1,11 +1,19 @@
 package tax
 
 import (
+	"context"
+
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
 )
 
 type TaxServiceInterface interface {
-	CalculateTax(income float64, wht float64, allowances []schemas.Allowance) (float64, float64, error)
-	CalculateDetailedTax(income float64, wht float64, allowances []schemas.Allowance) ([]schemas.TaxLevel, float64, float64, error)
-	CalculateTaxFromCSV(records []schemas.CSVObjectFormat) (schemas.CSVResponse, error)
+	CalculateTax(ctx context.Context, income float64, wht float64, allowances []schemas.Allowance) (float64, float64, error)
+	CalculateDetailedTax(ctx context.Context, income float64, wht float64, allowances []schemas.Allowance) ([]schemas.TaxLevel, float64, float64, error)
+	CalculateTaxBreakdown(ctx context.Context, income float64, wht float64, allowances []schemas.Allowance) (schemas.TaxCalculationV2Response, error)
+	GrossUp(ctx context.Context, targetNetIncome float64, allowances []schemas.Allowance, whtPolicy string, whtRate float64) (schemas.GrossUpResponse, error)
+	CompareScenarios(ctx context.Context, base schemas.TaxCalculationRequest, variants []schemas.ScenarioVariant) (schemas.ScenarioComparisonResponse, error)
+	OptimizeDeductions(ctx context.Context, income float64, wht float64, allowances []schemas.Allowance, budget float64, items []string) (schemas.DeductionOptimizationResponse, error)
+	CalculateWithholding(ctx context.Context, monthlySalary float64, payments []schemas.MonthlyPayment, allowances []schemas.Allowance) (schemas.WithholdingResponse, error)
+	ProjectTax(ctx context.Context, startYear int, startingIncome float64, growthRate float64, whtRate float64, plan []schemas.YearAllowances) (schemas.ProjectionResponse, error)
+	CalculateTaxFromCSV(ctx context.Context, records []schemas.CSVObjectFormat) (schemas.CSVResponseV2, error)
 }
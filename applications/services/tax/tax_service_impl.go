@@ -1,43 +1,63 @@
 package tax
 
 import (
+	"context"
+	"log/slog"
+	"math"
+	"runtime"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
 	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/repository"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/tracing"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"github.com/thitiphum-bluesage/assessment-tax/utilities"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/message"
 )
 
 type taxService struct {
-	taxRepo repository.TaxDeductionConfigRepositoryInterface
+	taxRepo    repository.TaxDeductionConfigRepositoryInterface
+	csvWorkers int
 }
 
-func NewTaxService(taxRepo repository.TaxDeductionConfigRepositoryInterface) TaxServiceInterface {
-	return &taxService{
-		taxRepo: taxRepo,
+// TaxServiceOption customises a tax service created by NewTaxService.
+type TaxServiceOption func(*taxService)
+
+// WithCSVWorkers sets how many workers calculate CSV records in parallel.
+// Values below 1 fall back to one worker per CPU.
+func WithCSVWorkers(workers int) TaxServiceOption {
+	return func(s *taxService) {
+		if workers < 1 {
+			workers = runtime.NumCPU()
+		}
+		s.csvWorkers = workers
 	}
 }
 
+func NewTaxService(taxRepo repository.TaxDeductionConfigRepositoryInterface, opts ...TaxServiceOption) TaxServiceInterface {
+	service := &taxService{
+		taxRepo:    taxRepo,
+		csvWorkers: runtime.NumCPU(),
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
 // This used for Story 1,2,3
-func (s *taxService) CalculateTax(income float64, wht float64, allowances []schemas.Allowance) (float64, float64, error) {
-	config, err := s.taxRepo.GetConfig()
+func (s *taxService) CalculateTax(ctx context.Context, income float64, wht float64, allowances []schemas.Allowance) (_ float64, _ float64, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.CalculateTax")
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	allowancesDeduction := config.PersonalDeduction
-	for _, allowance := range allowances {
-		if allowance.AllowanceType == "donation" {
-			if allowance.Amount > config.DonationDeductionMax {
-				allowancesDeduction += config.DonationDeductionMax
-			} else {
-				allowancesDeduction += allowance.Amount
-			}
-		} else {
-			if allowance.Amount > config.KReceiptDeductionMax {
-				allowancesDeduction += config.KReceiptDeductionMax
-			} else {
-				allowancesDeduction += allowance.Amount
-			}
-		}
-	}
+	allowancesDeduction := acceptAllowances(config, allowances)
 
 	incomeAfterDeduct := income - allowancesDeduction
 	if incomeAfterDeduct < 0 {
@@ -53,38 +73,27 @@ func (s *taxService) CalculateTax(income float64, wht float64, allowances []sche
 		netTax = 0
 	}
 
+	metrics.TaxCalculations.WithLabelValues(metrics.TaxOutcome(netTax, taxRefund)).Inc()
 	return netTax, taxRefund, nil
 }
 
-func (s *taxService) CalculateDetailedTax(income, wht float64, allowances []schemas.Allowance) ([]schemas.TaxLevel, float64, float64, error) {
-	config, err := s.taxRepo.GetConfig()
+func (s *taxService) CalculateDetailedTax(ctx context.Context, income, wht float64, allowances []schemas.Allowance) (_ []schemas.TaxLevel, _ float64, _ float64, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.CalculateDetailedTax")
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
 	if err != nil {
 		return nil, 0, 0, err
 	}
 
-	allowancesDeduction := config.PersonalDeduction
-	for _, allowance := range allowances {
-		if allowance.AllowanceType == "donation" {
-			if allowance.Amount > config.DonationDeductionMax {
-				allowancesDeduction += config.DonationDeductionMax
-			} else {
-				allowancesDeduction += allowance.Amount
-			}
-		} else {
-			if allowance.Amount > config.KReceiptDeductionMax {
-				allowancesDeduction += config.KReceiptDeductionMax
-			} else {
-				allowancesDeduction += allowance.Amount
-			}
-		}
-	}
+	allowancesDeduction := acceptAllowances(config, allowances)
 
 	incomeAfterDeduct := income - allowancesDeduction
 	if incomeAfterDeduct < 0 {
 		incomeAfterDeduct = 0
 	}
 
-	taxLevels, tax := calculateProgressiveTaxWithDetails(incomeAfterDeduct)
+	taxLevels, tax := calculateProgressiveTaxWithDetails(incomeAfterDeduct, taxLevelPrinter(ctx))
 
 	netTax := tax - wht
 	taxRefund := 0.0
@@ -93,50 +102,440 @@ func (s *taxService) CalculateDetailedTax(income, wht float64, allowances []sche
 		netTax = 0
 	}
 
+	metrics.TaxCalculations.WithLabelValues(metrics.TaxOutcome(netTax, taxRefund)).Inc()
 	return taxLevels, netTax, taxRefund, nil
 
 }
 
-func (s *taxService) CalculateTaxFromCSV(records []schemas.CSVObjectFormat) (schemas.CSVResponse, error) {
-	config, err := s.taxRepo.GetConfig()
+// CalculateTaxBreakdown returns the /v2 calculation: every deduction line with
+// the cap applied and why, the taxable income, gross tax, WHT credited and net
+// tax, and the bounds and rate of every bracket.
+func (s *taxService) CalculateTaxBreakdown(ctx context.Context, income, wht float64, allowances []schemas.Allowance) (_ schemas.TaxCalculationV2Response, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.CalculateTaxBreakdown")
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
+	if err != nil {
+		return schemas.TaxCalculationV2Response{}, err
+	}
+
+	breakdown := calculateTaxBreakdown(ctx, config, income, wht, allowances)
+	metrics.TaxCalculations.WithLabelValues(metrics.TaxOutcome(breakdown.Tax, breakdown.TaxRefund)).Inc()
+	return breakdown, nil
+}
+
+// GrossUp finds the gross income that leaves targetNetIncome after tax and
+// returns the calculation for it, with WHT withheld as whtPolicy says.
+func (s *taxService) GrossUp(ctx context.Context, targetNetIncome float64, allowances []schemas.Allowance, whtPolicy string, whtRate float64) (_ schemas.GrossUpResponse, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.GrossUp", attribute.String("tax.wht_policy", whtPolicy))
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
 	if err != nil {
-		return schemas.CSVResponse{}, err
+		return schemas.GrossUpResponse{}, err
 	}
 
-	var response schemas.CSVResponse
+	deductions := acceptAllowances(config, allowances)
+	grossIncome := grossIncomeForNet(targetNetIncome, deductions)
+	grossTax := calculateProgressiveTax(math.Max(grossIncome-deductions, 0))
+
+	wht := 0.0
+	switch whtPolicy {
+	case schemas.WHTPolicyFull:
+		wht = grossTax
+	case schemas.WHTPolicyRate:
+		wht = utilities.FormatToTwoDecimals(grossIncome * whtRate)
+	}
+
+	return schemas.GrossUpResponse{
+		TargetNetIncome:          targetNetIncome,
+		GrossIncome:              grossIncome,
+		NetIncome:                utilities.FormatToTwoDecimals(grossIncome - grossTax),
+		WHTPolicy:                whtPolicy,
+		TaxCalculationV2Response: calculateTaxBreakdown(ctx, config, grossIncome, wht, allowances),
+	}, nil
+}
 
-	for _, record := range records {
-		totalIncome := record.TotalIncome
-		wht := record.WHT
-		donation := record.Donation
-		k_receipt := record.KReceipt
+// CompareScenarios calculates the base and every variant with a single read
+// of the deduction configuration, so an admin update cannot land between them,
+// and reports how each variant differs from the base.
+func (s *taxService) CompareScenarios(ctx context.Context, base schemas.TaxCalculationRequest, variants []schemas.ScenarioVariant) (_ schemas.ScenarioComparisonResponse, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.CompareScenarios", attribute.Int("tax.scenarios", len(variants)))
+	defer func() { tracing.End(span, err) }()
 
-		if donation > config.DonationDeductionMax {
-			donation = config.DonationDeductionMax
+	config, err := s.taxRepo.GetConfig(ctx)
+	if err != nil {
+		return schemas.ScenarioComparisonResponse{}, err
+	}
+
+	calculate := func(req schemas.TaxCalculationRequest) schemas.TaxCalculationV2Response {
+		return calculateTaxBreakdown(ctx, config, *req.TotalIncome, *req.WHT, req.Allowances)
+	}
+
+	response := schemas.ScenarioComparisonResponse{
+		ConfigVersion: config.Version,
+		Base:          schemas.ScenarioResult{Name: "base", Result: calculate(base)},
+		Variants:      make([]schemas.ScenarioResult, len(variants)),
+	}
+	for i, variant := range variants {
+		result := calculate(variant.TaxCalculationRequest)
+		response.Variants[i] = schemas.ScenarioResult{
+			Name:       variant.Name,
+			Result:     result,
+			Difference: scenarioDifference(response.Base.Result, result),
 		}
-		if k_receipt > config.KReceiptDeductionMax {
-			k_receipt = config.KReceiptDeductionMax
+	}
+	return response, nil
+}
+
+// scenarioDifference subtracts the base from a variant, bracket by bracket.
+func scenarioDifference(base, variant schemas.TaxCalculationV2Response) *schemas.ScenarioDifference {
+	diff := func(variant, base float64) float64 {
+		return utilities.FormatToTwoDecimals(variant - base)
+	}
+
+	taxLevels := make([]schemas.TaxLevel, len(variant.TaxLevel))
+	for i, level := range variant.TaxLevel {
+		taxLevels[i] = schemas.TaxLevel{Level: level.Level, Tax: diff(level.Tax, base.TaxLevel[i].Tax)}
+	}
+	return &schemas.ScenarioDifference{
+		Tax:             diff(variant.Tax, base.Tax),
+		TaxRefund:       diff(variant.TaxRefund, base.TaxRefund),
+		TaxableIncome:   diff(variant.TaxableIncome, base.TaxableIncome),
+		TotalDeductions: diff(variant.TotalDeductions, base.TotalDeductions),
+		GrossTax:        diff(variant.GrossTax, base.GrossTax),
+		TaxLevel:        taxLevels,
+	}
+}
+
+// optimizableAllowances are the allowance types a budget is allocated to by
+// default, in order. Every baht allowed reduces taxable income by one baht,
+// so the order does not change the tax.
+var optimizableAllowances = domains.AllowanceTypes
+
+// OptimizeDeductions spends budget on the room left under the cap of each of
+// items, or of every optimizable allowance type when items is empty, on top of
+// the allowances already claimed, until taxable income reaches the 0%
+// bracket. Tax is convex in taxable income, so spending up to that point
+// always gives the lowest tax the budget can buy.
+func (s *taxService) OptimizeDeductions(ctx context.Context, income, wht float64, allowances []schemas.Allowance, budget float64, items []string) (_ schemas.DeductionOptimizationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.OptimizeDeductions")
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
+	if err != nil {
+		return schemas.DeductionOptimizationResponse{}, err
+	}
+
+	if len(items) == 0 {
+		items = optimizableAllowances
+	}
+	before := calculateTaxBreakdown(ctx, config, income, wht, allowances)
+
+	claimed := map[string]float64{}
+	for _, allowance := range allowances {
+		claimed[allowance.AllowanceType] += allowance.Amount
+	}
+	allocation := make([]schemas.DeductionAllocation, len(items))
+	totalRoom := 0.0
+	for i, allowanceType := range items {
+		room := math.Max(allowanceCap(config, allowanceType)-claimed[allowanceType], 0)
+		allocation[i] = schemas.DeductionAllocation{AllowanceType: allowanceType, Room: room}
+		totalRoom += room
+	}
+
+	saturationPoint := utilities.FormatToTwoDecimals(math.Min(totalRoom, math.Max(before.TaxableIncome-taxFreeIncome(), 0)))
+	spent := math.Min(budget, saturationPoint)
+
+	remaining := spent
+	for i := range allocation {
+		allocation[i].Amount = math.Min(remaining, allocation[i].Room)
+		remaining -= allocation[i].Amount
+		claimed[allocation[i].AllowanceType] += allocation[i].Amount
+	}
+
+	optimized := make([]schemas.Allowance, 0, len(optimizableAllowances))
+	for _, allowanceType := range optimizableAllowances {
+		if claimed[allowanceType] > 0 {
+			optimized = append(optimized, schemas.Allowance{AllowanceType: allowanceType, Amount: claimed[allowanceType]})
 		}
+	}
+	after := calculateTaxBreakdown(ctx, config, income, wht, optimized)
+
+	taxSaved := utilities.FormatToTwoDecimals(before.GrossTax - after.GrossTax)
+	taxSavedPerBaht := 0.0
+	if spent > 0 {
+		taxSavedPerBaht = math.Round(taxSaved/spent*10000) / 10000
+	}
 
-		totalIncomeAfterDeduct := totalIncome - (donation + k_receipt + config.PersonalDeduction)
+	return schemas.DeductionOptimizationResponse{
+		Budget:          budget,
+		Spent:           spent,
+		Unspent:         utilities.FormatToTwoDecimals(budget - spent),
+		Allocation:      allocation,
+		TaxBefore:       before.GrossTax,
+		TaxAfter:        after.GrossTax,
+		TaxSaved:        taxSaved,
+		TaxSavedPerBaht: taxSavedPerBaht,
+		SaturationPoint: saturationPoint,
+		Result:          after,
+	}, nil
+}
+
+// CalculateWithholding returns the monthly PND1 withholding schedule for a
+// year of pay, using the same allowances and brackets as the annual
+// calculation, which is returned for the year's pay and withholding.
+func (s *taxService) CalculateWithholding(ctx context.Context, monthlySalary float64, payments []schemas.MonthlyPayment, allowances []schemas.Allowance) (_ schemas.WithholdingResponse, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.CalculateWithholding", attribute.Int("payroll.payments", len(payments)))
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
+	if err != nil {
+		return schemas.WithholdingResponse{}, err
+	}
+
+	schedule := withholdingSchedule(monthlySalary, payments, acceptAllowances(config, allowances))
+	december := schedule[len(schedule)-1]
+	result := calculateTaxBreakdown(ctx, config, december.ProjectedAnnualIncome, december.WithheldToDate, allowances)
+
+	return schemas.WithholdingResponse{
+		AnnualIncome:  december.ProjectedAnnualIncome,
+		AnnualTax:     result.GrossTax,
+		TotalWithheld: december.WithheldToDate,
+		Schedule:      schedule,
+		Result:        result,
+	}, nil
+}
 
-		if totalIncomeAfterDeduct < 0 {
-			totalIncomeAfterDeduct = 0
+// ProjectTax calculates utilities.ProjectionYears tax years from startYear,
+// with income growing by growthRate a year and whtRate of it withheld. Each
+// year uses its tax year configuration when there is one and MainConfig
+// otherwise, and the allowances of the latest plan entry up to that year.
+func (s *taxService) ProjectTax(ctx context.Context, startYear int, startingIncome, growthRate, whtRate float64, plan []schemas.YearAllowances) (_ schemas.ProjectionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.ProjectTax", attribute.Int("tax.start_year", startYear))
+	defer func() { tracing.End(span, err) }()
+
+	latest, err := s.taxRepo.GetConfig(ctx)
+	if err != nil {
+		return schemas.ProjectionResponse{}, err
+	}
+	taxYearConfigs, err := s.taxRepo.GetTaxYearConfigs(ctx)
+	if err != nil {
+		return schemas.ProjectionResponse{}, err
+	}
+	configs := make(map[int]*domains.TaxDeductionConfig, len(taxYearConfigs))
+	for i := range taxYearConfigs {
+		configs[*taxYearConfigs[i].TaxYear] = &taxYearConfigs[i]
+	}
+
+	plans := make(map[int][]schemas.Allowance, len(plan))
+	for _, year := range plan {
+		plans[year.Year] = year.Allowances
+	}
+
+	response := schemas.ProjectionResponse{Years: make([]schemas.ProjectionYear, utilities.ProjectionYears)}
+	var allowances []schemas.Allowance
+	for i := range response.Years {
+		year := startYear + i
+		config, source := configs[year], schemas.ProjectionConfigTaxYear
+		if config == nil {
+			config, source = latest, schemas.ProjectionConfigLatest
 		}
+		if yearAllowances, ok := plans[year]; ok {
+			allowances = yearAllowances
+		}
+
+		income := utilities.FormatToTwoDecimals(startingIncome * math.Pow(1+growthRate, float64(i)))
+		wht := utilities.FormatToTwoDecimals(income * whtRate)
+		result := calculateTaxBreakdown(ctx, config, income, wht, allowances)
 
-		tax := calculateProgressiveTax(totalIncomeAfterDeduct)
-		netTax := tax - wht
-		if netTax < 0 {
-			response.Taxes = append(response.Taxes, schemas.CSVResponseMember{
-				TotalIncome: totalIncome,
-				TaxRefund:   -netTax,
-			})
-		} else {
-			response.Taxes = append(response.Taxes, schemas.CSVResponseMember{
-				TotalIncome: totalIncome,
-				Tax:         netTax,
-			})
+		response.TotalIncome = utilities.FormatToTwoDecimals(response.TotalIncome + income)
+		response.TotalTax = utilities.FormatToTwoDecimals(response.TotalTax + result.Tax)
+		response.TotalTaxRefund = utilities.FormatToTwoDecimals(response.TotalTaxRefund + result.TaxRefund)
+		response.Years[i] = schemas.ProjectionYear{
+			Year:                year,
+			Income:              income,
+			ConfigSource:        source,
+			ConfigName:          config.ConfigName,
+			ConfigVersion:       config.Version,
+			WHT:                 wht,
+			Tax:                 result.Tax,
+			TaxRefund:           result.TaxRefund,
+			CumulativeTax:       response.TotalTax,
+			CumulativeTaxRefund: response.TotalTaxRefund,
+			Result:              result,
 		}
 	}
 	return response, nil
 }
+
+// calculateTaxBreakdown is the /v2 calculation for one income with a given
+// configuration.
+func calculateTaxBreakdown(ctx context.Context, config *domains.TaxDeductionConfig, income, wht float64, allowances []schemas.Allowance) schemas.TaxCalculationV2Response {
+	deductions, allowancesDeduction := deductionLines(config, allowances, i18n.Printer(ctx))
+
+	incomeAfterDeduct := income - allowancesDeduction
+	if incomeAfterDeduct < 0 {
+		incomeAfterDeduct = 0
+	}
+
+	taxLevels, tax := calculateProgressiveTaxBrackets(incomeAfterDeduct, taxLevelPrinter(ctx))
+
+	netTax := utilities.FormatToTwoDecimals(tax - wht)
+	payable, taxRefund := netTax, 0.0
+	if netTax < 0 {
+		payable, taxRefund = 0, -netTax
+	}
+
+	return schemas.TaxCalculationV2Response{
+		Tax:             payable,
+		TaxRefund:       taxRefund,
+		TaxableIncome:   incomeAfterDeduct,
+		TotalDeductions: allowancesDeduction,
+		GrossTax:        tax,
+		WHTCredited:     wht,
+		NetTax:          netTax,
+		TaxRates:        taxRates(income, incomeAfterDeduct, tax),
+		Deductions:      deductions,
+		TaxLevel:        taxLevels,
+	}
+}
+
+// acceptAllowances returns the total deduction for a calculation: the
+// personal deduction plus every allowance capped at its configured maximum.
+func acceptAllowances(config *domains.TaxDeductionConfig, allowances []schemas.Allowance) float64 {
+	total := config.PersonalDeduction
+	for _, allowance := range allowances {
+		total += math.Min(allowance.Amount, allowanceCap(config, allowance.AllowanceType))
+	}
+	return total
+}
+
+// deductionLines explains acceptAllowances line by line, starting with the
+// personal deduction, in the language of the printer.
+func deductionLines(config *domains.TaxDeductionConfig, allowances []schemas.Allowance, printer *message.Printer) ([]schemas.DeductionLine, float64) {
+	lines := make([]schemas.DeductionLine, 0, len(allowances)+1)
+	lines = append(lines, schemas.DeductionLine{
+		AllowanceType: "personal",
+		Claimed:       config.PersonalDeduction,
+		Allowed:       config.PersonalDeduction,
+		Reason:        schemas.DeductionReasonFixed,
+		Explanation:   printer.Sprintf("Personal deduction of %v applies to every taxpayer", config.PersonalDeduction),
+	})
+	total := config.PersonalDeduction
+
+	for _, allowance := range allowances {
+		max := allowanceCap(config, allowance.AllowanceType)
+		line := schemas.DeductionLine{
+			AllowanceType: allowance.AllowanceType,
+			Claimed:       allowance.Amount,
+			Allowed:       allowance.Amount,
+			Cap:           &max,
+			Reason:        schemas.DeductionReasonWithinCap,
+			Explanation:   printer.Sprintf("Claimed amount is within the cap of %v", max),
+		}
+		if allowance.Amount > max {
+			line.Allowed = max
+			line.Reason = schemas.DeductionReasonCapped
+			line.Explanation = printer.Sprintf("Capped at the maximum of %v", max)
+		}
+		lines = append(lines, line)
+		total += line.Allowed
+	}
+	return lines, total
+}
+
+// allowanceCap returns the configured maximum for an allowance type. Types
+// that are not in domains.AllowanceTypes are rejected by validation and
+// allowed nothing here.
+func allowanceCap(config *domains.TaxDeductionConfig, allowanceType string) float64 {
+	switch allowanceType {
+	case domains.AllowanceDonation:
+		return config.DonationDeductionMax
+	case domains.AllowanceKReceipt:
+		return config.KReceiptDeductionMax
+	case domains.AllowanceInsurance:
+		return config.InsuranceDeductionMax
+	case domains.AllowanceRMF:
+		return config.RMFDeductionMax
+	case domains.AllowanceSSF:
+		return config.SSFDeductionMax
+	}
+	return 0
+}
+
+func (s *taxService) CalculateTaxFromCSV(ctx context.Context, records []schemas.CSVObjectFormat) (_ schemas.CSVResponseV2, err error) {
+	ctx, span := tracing.Start(ctx, "taxService.CalculateTaxFromCSV",
+		attribute.Int("csv.rows", len(records)),
+		attribute.Int("csv.workers", s.csvWorkers),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	config, err := s.taxRepo.GetConfig(ctx)
+	if err != nil {
+		return schemas.CSVResponseV2{}, err
+	}
+
+	taxes := make([]schemas.CSVResponseV2Member, len(records))
+	err = runInChunks(ctx, len(records), s.csvWorkers, func(start, end int) {
+		for i := start; i < end; i++ {
+			taxes[i] = calculateCSVRecordTax(config, records[i])
+		}
+	})
+	if err != nil {
+		return schemas.CSVResponseV2{}, err
+	}
+
+	recordCSVTaxOutcomes(taxes)
+	slog.InfoContext(ctx, "Calculated taxes from CSV", "rows", len(records), "workers", s.csvWorkers)
+	return schemas.CSVResponseV2{Taxes: taxes}, nil
+}
+
+// recordCSVTaxOutcomes counts outcomes locally and adds them once, rather than
+// touching the shared counters from every worker for every row.
+func recordCSVTaxOutcomes(taxes []schemas.CSVResponseV2Member) {
+	outcomes := map[string]int{}
+	for _, tax := range taxes {
+		outcomes[metrics.TaxOutcome(tax.Tax, tax.TaxRefund)]++
+	}
+	for outcome, count := range outcomes {
+		metrics.TaxCalculations.WithLabelValues(outcome).Add(float64(count))
+	}
+	metrics.CSVRowsProcessed.Add(float64(len(taxes)))
+}
+
+func calculateCSVRecordTax(config *domains.TaxDeductionConfig, record schemas.CSVObjectFormat) schemas.CSVResponseV2Member {
+	totalIncome := record.TotalIncome
+	wht := record.WHT
+
+	totalIncomeAfterDeduct := totalIncome - acceptAllowances(config, record.Allowances)
+	if totalIncomeAfterDeduct < 0 {
+		totalIncomeAfterDeduct = 0
+	}
+
+	tax := calculateProgressiveTax(totalIncomeAfterDeduct)
+	netTax := tax - wht
+	rates := taxRates(totalIncome, totalIncomeAfterDeduct, tax)
+
+	if netTax < 0 {
+		return schemas.CSVResponseV2Member{
+			CSVResponseMember: schemas.CSVResponseMember{TotalIncome: totalIncome, TaxRefund: -netTax},
+			TaxRates:          rates,
+		}
+	}
+	return schemas.CSVResponseV2Member{
+		CSVResponseMember: schemas.CSVResponseMember{TotalIncome: totalIncome, Tax: netTax},
+		TaxRates:          rates,
+	}
+}
+
+// taxRates places taxable income in the brackets and relates the gross tax
+// to gross income.
+func taxRates(grossIncome, taxableIncome, grossTax float64) schemas.TaxRates {
+	marginalRate, distance := marginalBracket(taxableIncome)
+	return schemas.TaxRates{
+		MarginalRate:          marginalRate,
+		EffectiveRate:         effectiveRate(grossTax, grossIncome),
+		DistanceToNextBracket: distance,
+	}
+}
@@ -1,19 +1,26 @@
 package tax
 
 import (
+	"context"
+	"fmt"
+	"runtime"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"golang.org/x/text/language"
 )
 
 type MockTaxRepo struct {
 	mock.Mock
 }
 
-func (m *MockTaxRepo) GetConfig() (*domains.TaxDeductionConfig, error) {
+func (m *MockTaxRepo) GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error) {
 	args := m.Called()
 	if config, ok := args.Get(0).(*domains.TaxDeductionConfig); ok {
 		return config, args.Error(1)
@@ -21,16 +28,49 @@ func (m *MockTaxRepo) GetConfig() (*domains.TaxDeductionConfig, error) {
 	return nil, args.Error(1)
 }
 
-func (m *MockTaxRepo) UpdatePersonalDeduction(amount float64) error {
-	args := m.Called(amount)
-	return args.Error(0)
+func (m *MockTaxRepo) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	args := m.Called()
+	if configs, ok := args.Get(0).([]domains.TaxDeductionConfig); ok {
+		return configs, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxRepo) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxRepo) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxRepo) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxRepo) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxRepo) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockTaxRepo) UpdateKReceiptDeductionMax(amount float64) error {
-	args := m.Called(amount)
+func (m *MockTaxRepo) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	args := m.Called(config)
 	return args.Error(0)
 }
 
+func (m *MockTaxRepo) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	args := m.Called(config, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestCalculateProgressiveTax(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -53,6 +93,38 @@ func TestCalculateProgressiveTax(t *testing.T) {
 	}
 }
 
+func baht(amount float64) *float64 {
+	return &amount
+}
+
+func TestMarginalBracket(t *testing.T) {
+	tests := []struct {
+		name     string
+		income   float64
+		rate     float64
+		distance *float64
+	}{
+		{"Zero income", 0, 0, baht(150000)},
+		{"Boundary of first bracket", 150000, 0, baht(0)},
+		{"Just into second bracket", 150000.5, 0.1, baht(349999.5)},
+		{"Middle of third bracket", 690000, 0.15, baht(310000)},
+		{"Top bracket", 2500000, 0.35, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, distance := marginalBracket(tt.income)
+			assert.Equal(t, tt.rate, rate)
+			assert.Equal(t, tt.distance, distance)
+		})
+	}
+}
+
+func TestEffectiveRate(t *testing.T) {
+	assert.Equal(t, 0.058, effectiveRate(29000, 500000))
+	assert.Equal(t, 0.0, effectiveRate(0, 0))
+}
+
 func TestCalculateTaxFromCSV(t *testing.T) {
 	mockRepo := new(MockTaxRepo)
 	service := NewTaxService(mockRepo)
@@ -60,27 +132,56 @@ func TestCalculateTaxFromCSV(t *testing.T) {
 		PersonalDeduction:    60000,
 		DonationDeductionMax: 100000,
 		KReceiptDeductionMax: 50000,
+		RMFDeductionMax:      500000,
 	}
 
 	mockRepo.On("GetConfig").Return(config, nil)
 
 	records := []schemas.CSVObjectFormat{
-		{TotalIncome: 500000, WHT: 0, Donation: 0},
-		{TotalIncome: 600000, WHT: 40000, Donation: 20000},
-		{TotalIncome: 750000, WHT: 50000, Donation: 15000},
+		{TotalIncome: 500000, WHT: 0},
+		{TotalIncome: 600000, WHT: 40000, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 20000}}},
+		{TotalIncome: 750000, WHT: 50000, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 15000}}},
+		{TotalIncome: 1000000, WHT: 0, Allowances: []schemas.Allowance{{AllowanceType: "rmf", Amount: 600000}}},
 	}
 
-	response, err := service.CalculateTaxFromCSV(records)
+	response, err := service.CalculateTaxFromCSV(context.Background(), records)
 	assert.NoError(t, err)
 
-	expectedTaxes := []schemas.CSVResponseMember{
-		{TotalIncome: 500000, Tax: 29000},
-		{TotalIncome: 600000, TaxRefund: 2000},
-		{TotalIncome: 750000, Tax: 11250},
+	expectedTaxes := []schemas.CSVResponseV2Member{
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 500000, Tax: 29000}, TaxRates: schemas.TaxRates{MarginalRate: 0.1, EffectiveRate: 0.058, DistanceToNextBracket: baht(60000)}},
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 600000, TaxRefund: 2000}, TaxRates: schemas.TaxRates{MarginalRate: 0.15, EffectiveRate: 0.0633, DistanceToNextBracket: baht(480000)}},
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 750000, Tax: 11250}, TaxRates: schemas.TaxRates{MarginalRate: 0.15, EffectiveRate: 0.0817, DistanceToNextBracket: baht(325000)}},
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 1000000, Tax: 29000}, TaxRates: schemas.TaxRates{MarginalRate: 0.1, EffectiveRate: 0.029, DistanceToNextBracket: baht(60000)}},
 	}
 	assert.Equal(t, expectedTaxes, response.Taxes)
 }
 
+func TestCalculateTaxFromCSV_RecordsOutcomes(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		PersonalDeduction:    60000,
+		DonationDeductionMax: 100000,
+		KReceiptDeductionMax: 50000,
+	}, nil)
+
+	taxOutcomes := metrics.TaxCalculations.WithLabelValues(metrics.OutcomeTax)
+	refundOutcomes := metrics.TaxCalculations.WithLabelValues(metrics.OutcomeRefund)
+	taxBefore, refundBefore := testutil.ToFloat64(taxOutcomes), testutil.ToFloat64(refundOutcomes)
+	rowsBefore := testutil.ToFloat64(metrics.CSVRowsProcessed)
+
+	_, err := service.CalculateTaxFromCSV(context.Background(), []schemas.CSVObjectFormat{
+		{TotalIncome: 500000, WHT: 0},
+		{TotalIncome: 600000, WHT: 40000, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 20000}}},
+		{TotalIncome: 750000, WHT: 50000, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 15000}}},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, taxBefore+2, testutil.ToFloat64(taxOutcomes))
+	assert.Equal(t, refundBefore+1, testutil.ToFloat64(refundOutcomes))
+	assert.Equal(t, rowsBefore+3, testutil.ToFloat64(metrics.CSVRowsProcessed))
+}
+
 func TestCalculateDetailedTax(t *testing.T) {
 	mockRepo := new(MockTaxRepo)
 	service := NewTaxService(mockRepo)
@@ -88,6 +189,7 @@ func TestCalculateDetailedTax(t *testing.T) {
 		PersonalDeduction:    60000,
 		DonationDeductionMax: 100000,
 		KReceiptDeductionMax: 50000,
+		RMFDeductionMax:      500000,
 	}
 
 	mockRepo.On("GetConfig").Return(config, nil)
@@ -97,7 +199,7 @@ func TestCalculateDetailedTax(t *testing.T) {
 		{AllowanceType: "donation", Amount: 100000},
 	}
 
-	taxLevels, netTax, taxRefund, err := service.CalculateDetailedTax(900000, 7000, allowances)
+	taxLevels, netTax, taxRefund, err := service.CalculateDetailedTax(context.Background(), 900000, 7000, allowances)
 	assert.NoError(t, err)
 
 	expectedTaxLevels := []schemas.TaxLevel{
@@ -114,3 +216,550 @@ func TestCalculateDetailedTax(t *testing.T) {
 	assert.Equal(t, expectedNetTax, netTax)
 	assert.Equal(t, expectedTaxRefund, taxRefund)
 }
+
+func TestCalculateTaxBreakdown(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		PersonalDeduction:    60000,
+		DonationDeductionMax: 100000,
+		KReceiptDeductionMax: 50000,
+	}, nil)
+
+	allowances := []schemas.Allowance{
+		{AllowanceType: "k-receipt", Amount: 200000},
+		{AllowanceType: "donation", Amount: 100000},
+	}
+
+	breakdown, err := service.CalculateTaxBreakdown(i18n.WithLocale(context.Background(), language.English), 900000, 7000, allowances)
+	assert.NoError(t, err)
+
+	expected := schemas.TaxCalculationV2Response{
+		Tax:             56500,
+		TaxRefund:       0,
+		TaxableIncome:   690000,
+		TotalDeductions: 210000,
+		GrossTax:        63500,
+		WHTCredited:     7000,
+		NetTax:          56500,
+		TaxRates:        schemas.TaxRates{MarginalRate: 0.15, EffectiveRate: 0.0706, DistanceToNextBracket: baht(310000)},
+		Deductions: []schemas.DeductionLine{
+			{AllowanceType: "personal", Claimed: 60000, Allowed: 60000, Reason: schemas.DeductionReasonFixed, Explanation: "Personal deduction of 60,000 applies to every taxpayer"},
+			{AllowanceType: "k-receipt", Claimed: 200000, Allowed: 50000, Cap: baht(50000), Reason: schemas.DeductionReasonCapped, Explanation: "Capped at the maximum of 50,000"},
+			{AllowanceType: "donation", Claimed: 100000, Allowed: 100000, Cap: baht(100000), Reason: schemas.DeductionReasonWithinCap, Explanation: "Claimed amount is within the cap of 100,000"},
+		},
+		TaxLevel: []schemas.TaxBracket{
+			{Level: "0-150,000", LowerBound: 0, UpperBound: baht(150000), Rate: 0, Tax: 0},
+			{Level: "150,001-500,000", LowerBound: 150000, UpperBound: baht(500000), Rate: 0.1, Tax: 35000},
+			{Level: "500,001-1,000,000", LowerBound: 500000, UpperBound: baht(1000000), Rate: 0.15, Tax: 28500},
+			{Level: "1,000,001-2,000,000", LowerBound: 1000000, UpperBound: baht(2000000), Rate: 0.2, Tax: 0},
+			{Level: "2,000,001 and above", LowerBound: 2000000, UpperBound: nil, Rate: 0.35, Tax: 0},
+		},
+	}
+	assert.Equal(t, expected, breakdown)
+}
+
+func TestCalculateTaxBreakdown_Refund(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil)
+
+	breakdown, err := service.CalculateTaxBreakdown(context.Background(), 50000, 1000, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, breakdown.Tax)
+	assert.Equal(t, 1000.0, breakdown.TaxRefund)
+	assert.Equal(t, 0.0, breakdown.TaxableIncome)
+	assert.Equal(t, 60000.0, breakdown.TotalDeductions)
+	assert.Equal(t, 0.0, breakdown.GrossTax)
+	assert.Equal(t, 1000.0, breakdown.WHTCredited)
+	assert.Equal(t, -1000.0, breakdown.NetTax)
+}
+
+func TestCalculateTaxBreakdown_ThaiExplanations(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000, DonationDeductionMax: 100000}, nil)
+
+	ctx := i18n.WithLocale(context.Background(), language.Thai)
+	breakdown, err := service.CalculateTaxBreakdown(ctx, 500000, 0, []schemas.Allowance{{AllowanceType: "donation", Amount: 150000}})
+	assert.NoError(t, err)
+
+	explanations := make([]string, len(breakdown.Deductions))
+	for i, line := range breakdown.Deductions {
+		explanations[i] = line.Explanation
+	}
+	assert.Equal(t, []string{"ค่าลดหย่อนส่วนตัว 60,000 ใช้กับผู้เสียภาษีทุกคน", "ลดหย่อนได้สูงสุด 100,000"}, explanations)
+}
+
+func TestAllowanceCap(t *testing.T) {
+	config := domains.DefaultTaxDeductionConfig()
+	assert.Equal(t, 100000.0, allowanceCap(&config, "donation"))
+	assert.Equal(t, 50000.0, allowanceCap(&config, "k-receipt"))
+	assert.Equal(t, 100000.0, allowanceCap(&config, "insurance"))
+	assert.Equal(t, 500000.0, allowanceCap(&config, "rmf"))
+	assert.Equal(t, 200000.0, allowanceCap(&config, "ssf"))
+	assert.Equal(t, 0.0, allowanceCap(&config, "bonus"), "unknown types are allowed nothing")
+}
+
+func TestGrossIncomeForNet(t *testing.T) {
+	tests := []struct {
+		name       string
+		net        float64
+		deductions float64
+		want       float64
+	}{
+		{"Covered by deductions", 50000, 60000, 50000},
+		{"First bracket", 200000, 60000, 200000},
+		{"Second bracket", 400000, 60000, 421111.11},
+		{"Upper bound of second bracket", 525000, 60000, 560000},
+		{"Top bracket", 3000000, 60000, 3983076.92},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gross := grossIncomeForNet(tt.net, tt.deductions)
+			assert.Equal(t, tt.want, gross)
+			tax := calculateProgressiveTax(gross - tt.deductions)
+			assert.InDelta(t, tt.net, gross-tax, 0.005)
+		})
+	}
+}
+
+func TestGrossUp(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		PersonalDeduction:    60000,
+		DonationDeductionMax: 100000,
+		KReceiptDeductionMax: 50000,
+	}, nil)
+	allowances := []schemas.Allowance{{AllowanceType: "donation", Amount: 200000}}
+
+	tests := []struct {
+		policy    string
+		rate      float64
+		wht       float64
+		tax       float64
+		taxRefund float64
+	}{
+		{schemas.WHTPolicyFull, 0, 21111.11, 0, 0},
+		{schemas.WHTPolicyNone, 0, 0, 21111.11, 0},
+		{schemas.WHTPolicyRate, 0.05, 26055.56, 0, 4944.45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			result, err := service.GrossUp(context.Background(), 500000, allowances, tt.policy, tt.rate)
+			assert.NoError(t, err)
+			assert.Equal(t, 521111.11, result.GrossIncome)
+			assert.Equal(t, 500000.0, result.NetIncome)
+			assert.Equal(t, 361111.11, result.TaxableIncome)
+			assert.Equal(t, 21111.11, result.GrossTax)
+			assert.Equal(t, tt.wht, result.WHTCredited)
+			assert.Equal(t, tt.tax, result.Tax)
+			assert.Equal(t, tt.taxRefund, result.TaxRefund)
+			assert.Equal(t, tt.policy, result.WHTPolicy)
+		})
+	}
+}
+
+func TestCompareScenarios(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		PersonalDeduction:    60000,
+		DonationDeductionMax: 100000,
+		KReceiptDeductionMax: 50000,
+		Version:              3,
+	}, nil).Once()
+
+	income, wht := 800000.0, 60000.0
+	base := schemas.TaxCalculationRequest{TotalIncome: &income, WHT: &wht}
+	variants := []schemas.ScenarioVariant{{
+		Name: "max donation",
+		TaxCalculationRequest: schemas.TaxCalculationRequest{
+			TotalIncome: &income, WHT: &wht, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 100000}},
+		},
+	}}
+
+	comparison, err := service.CompareScenarios(context.Background(), base, variants)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	assert.Equal(t, int64(3), comparison.ConfigVersion)
+	assert.Equal(t, "base", comparison.Base.Name)
+	assert.Nil(t, comparison.Base.Difference)
+	assert.Equal(t, 11000.0, comparison.Base.Result.Tax)
+	if assert.Len(t, comparison.Variants, 1) {
+		variant := comparison.Variants[0]
+		assert.Equal(t, "max donation", variant.Name)
+		assert.Equal(t, 0.0, variant.Result.Tax)
+		assert.Equal(t, 4000.0, variant.Result.TaxRefund)
+		assert.Equal(t, &schemas.ScenarioDifference{
+			Tax:             -11000,
+			TaxRefund:       4000,
+			TaxableIncome:   -100000,
+			TotalDeductions: 100000,
+			GrossTax:        -15000,
+			TaxLevel: []schemas.TaxLevel{
+				{Level: "0-150,000", Tax: 0},
+				{Level: "150,001-500,000", Tax: 0},
+				{Level: "500,001-1,000,000", Tax: -15000},
+				{Level: "1,000,001-2,000,000", Tax: 0},
+				{Level: "2,000,001 ขึ้นไป", Tax: 0},
+			},
+		}, variant.Difference)
+	}
+}
+
+func TestOptimizeDeductions(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		PersonalDeduction:     60000,
+		DonationDeductionMax:  100000,
+		KReceiptDeductionMax:  50000,
+		InsuranceDeductionMax: 100000,
+		RMFDeductionMax:       500000,
+		SSFDeductionMax:       200000,
+	}, nil)
+
+	tests := []struct {
+		name            string
+		income          float64
+		allowances      []schemas.Allowance
+		budget          float64
+		items           []string
+		allocation      []schemas.DeductionAllocation
+		unspent         float64
+		taxBefore       float64
+		taxAfter        float64
+		taxSavedPerBaht float64
+		saturationPoint float64
+	}{
+		{
+			"Budget below the saturation point", 500000, nil, 100000, nil,
+			[]schemas.DeductionAllocation{
+				{AllowanceType: "donation", Amount: 100000, Room: 100000}, {AllowanceType: "k-receipt", Amount: 0, Room: 50000},
+				{AllowanceType: "insurance", Amount: 0, Room: 100000}, {AllowanceType: "rmf", Amount: 0, Room: 500000}, {AllowanceType: "ssf", Amount: 0, Room: 200000},
+			},
+			0, 29000, 19000, 0.1, 290000,
+		},
+		{
+			"Taxable income reaches the 0% bracket", 300000, nil, 200000, nil,
+			[]schemas.DeductionAllocation{
+				{AllowanceType: "donation", Amount: 90000, Room: 100000}, {AllowanceType: "k-receipt", Amount: 0, Room: 50000},
+				{AllowanceType: "insurance", Amount: 0, Room: 100000}, {AllowanceType: "rmf", Amount: 0, Room: 500000}, {AllowanceType: "ssf", Amount: 0, Room: 200000},
+			},
+			110000, 9000, 0, 0.1, 90000,
+		},
+		{
+			"Caps used up on top of claimed allowances", 1000000, []schemas.Allowance{{AllowanceType: "donation", Amount: 80000}}, 100000, nil,
+			[]schemas.DeductionAllocation{
+				{AllowanceType: "donation", Amount: 20000, Room: 20000}, {AllowanceType: "k-receipt", Amount: 50000, Room: 50000},
+				{AllowanceType: "insurance", Amount: 30000, Room: 100000}, {AllowanceType: "rmf", Amount: 0, Room: 500000}, {AllowanceType: "ssf", Amount: 0, Room: 200000},
+			},
+			0, 89000, 74000, 0.15, 710000,
+		},
+		{
+			"Every cap used up", 2000000, nil, 1000000, nil,
+			[]schemas.DeductionAllocation{
+				{AllowanceType: "donation", Amount: 100000, Room: 100000}, {AllowanceType: "k-receipt", Amount: 50000, Room: 50000},
+				{AllowanceType: "insurance", Amount: 100000, Room: 100000}, {AllowanceType: "rmf", Amount: 500000, Room: 500000}, {AllowanceType: "ssf", Amount: 200000, Room: 200000},
+			},
+			50000, 298000, 108500, 0.1995, 950000,
+		},
+		{
+			"RMF capped", 1000000, nil, 600000, []string{"rmf"},
+			[]schemas.DeductionAllocation{{AllowanceType: "rmf", Amount: 500000, Room: 500000}},
+			100000, 101000, 29000, 0.144, 500000,
+		},
+		{
+			"Only the items asked for", 500000, nil, 100000, []string{"k-receipt"},
+			[]schemas.DeductionAllocation{{AllowanceType: "k-receipt", Amount: 50000, Room: 50000}},
+			50000, 29000, 24000, 0.1, 50000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.OptimizeDeductions(context.Background(), tt.income, 0, tt.allowances, tt.budget, tt.items)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allocation, result.Allocation)
+			assert.Equal(t, tt.budget-tt.unspent, result.Spent)
+			assert.Equal(t, tt.unspent, result.Unspent)
+			assert.Equal(t, tt.taxBefore, result.TaxBefore)
+			assert.Equal(t, tt.taxAfter, result.TaxAfter)
+			assert.Equal(t, tt.taxBefore-tt.taxAfter, result.TaxSaved)
+			assert.Equal(t, tt.taxSavedPerBaht, result.TaxSavedPerBaht)
+			assert.Equal(t, tt.saturationPoint, result.SaturationPoint)
+			assert.Equal(t, tt.taxAfter, result.Result.GrossTax)
+		})
+	}
+}
+
+func TestWithholdingSchedule(t *testing.T) {
+	raise := 60000.0
+	schedule := withholdingSchedule(50000, []schemas.MonthlyPayment{
+		{Month: 4, Bonus: 100000},
+		{Month: 7, Salary: &raise},
+	}, 60000)
+
+	if !assert.Len(t, schedule, 12) {
+		return
+	}
+	assert.Equal(t, schemas.WithholdingMonth{
+		Month: 1, Salary: 50000, ProjectedAnnualIncome: 600000, ProjectedAnnualTax: 41000,
+		Withholding: 3416.67, WithheldToDate: 3416.67,
+	}, schedule[0])
+	assert.Equal(t, schemas.WithholdingMonth{
+		Month: 4, Salary: 50000, Bonus: 100000, ProjectedAnnualIncome: 700000, ProjectedAnnualTax: 56000,
+		Withholding: 18416.67, BonusWithholding: 15000, WithheldToDate: 28666.68,
+	}, schedule[3])
+	assert.Equal(t, 60000.0, schedule[6].Salary, "the raise applies from July on")
+	assert.Equal(t, 60000.0, schedule[11].Salary)
+	assert.Equal(t, 760000.0, schedule[11].ProjectedAnnualIncome)
+	assert.Equal(t, 65000.0, schedule[11].WithheldToDate, "December catches up to the tax on the year's pay")
+}
+
+func TestWithholdingSchedule_BelowTaxThreshold(t *testing.T) {
+	for _, month := range withholdingSchedule(15000, nil, 60000) {
+		assert.Equal(t, 0.0, month.Withholding)
+	}
+}
+
+func TestWithholdingSchedule_MidYearPayCut(t *testing.T) {
+	unpaid := 0.0
+	schedule := withholdingSchedule(300000, []schemas.MonthlyPayment{{Month: 6, Salary: &unpaid}}, 60000)
+
+	assert.Equal(t, 70750.0, schedule[4].Withholding)
+	assert.Equal(t, 353750.0, schedule[4].WithheldToDate)
+	for _, month := range schedule[5:] {
+		assert.Equal(t, 0.0, month.Withholding, "month %d", month.Month)
+		assert.Equal(t, 353750.0, month.WithheldToDate, "month %d", month.Month)
+	}
+}
+
+func TestCalculateWithholding_PayCutRefundsExcess(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil)
+
+	unpaid := 0.0
+	result, err := service.CalculateWithholding(context.Background(), 300000, []schemas.MonthlyPayment{{Month: 6, Salary: &unpaid}}, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1500000.0, result.AnnualIncome)
+	assert.Equal(t, 198000.0, result.AnnualTax)
+	assert.Equal(t, 353750.0, result.TotalWithheld)
+	assert.Equal(t, 0.0, result.Result.Tax)
+	assert.Equal(t, 155750.0, result.Result.TaxRefund)
+}
+
+func TestCalculateWithholding(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		PersonalDeduction:    60000,
+		DonationDeductionMax: 100000,
+	}, nil)
+
+	result, err := service.CalculateWithholding(context.Background(), 50000, []schemas.MonthlyPayment{{Month: 12, Bonus: 50000}},
+		[]schemas.Allowance{{AllowanceType: "donation", Amount: 40000}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 650000.0, result.AnnualIncome)
+	assert.Equal(t, 42500.0, result.AnnualTax)
+	assert.Equal(t, 42500.0, result.TotalWithheld)
+	assert.Equal(t, 7500.0, result.Schedule[11].BonusWithholding)
+	assert.Equal(t, 0.0, result.Result.Tax)
+	assert.Equal(t, 0.0, result.Result.TaxRefund)
+}
+
+func TestCalculateDetailedTax_LocalizedLevels(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil)
+
+	tests := []struct {
+		locale   language.Tag
+		expected []string
+	}{
+		{language.English, []string{"0-150,000", "150,001-500,000", "500,001-1,000,000", "1,000,001-2,000,000", "2,000,001 and above"}},
+		{language.Thai, []string{"0-150,000", "150,001-500,000", "500,001-1,000,000", "1,000,001-2,000,000", "2,000,001 ขึ้นไป"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale.String(), func(t *testing.T) {
+			ctx := i18n.WithLocale(context.Background(), tt.locale)
+			taxLevels, _, _, err := service.CalculateDetailedTax(ctx, 500000, 0, nil)
+			assert.NoError(t, err)
+
+			labels := make([]string, len(taxLevels))
+			for i, taxLevel := range taxLevels {
+				labels[i] = taxLevel.Level
+			}
+			assert.Equal(t, tt.expected, labels)
+		})
+	}
+}
+
+func TestCalculateTaxFromCSV_ParallelKeepsOrder(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	config := &domains.TaxDeductionConfig{
+		PersonalDeduction:    60000,
+		DonationDeductionMax: 100000,
+		KReceiptDeductionMax: 50000,
+	}
+	mockRepo.On("GetConfig").Return(config, nil)
+
+	records := generateCSVRecords(10000)
+	baseline := calculateTaxFromCSVSequentially(config, records)
+
+	for _, workers := range []int{1, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			result, err := NewTaxService(mockRepo, WithCSVWorkers(workers)).CalculateTaxFromCSV(context.Background(), records)
+			assert.NoError(t, err)
+			assert.Len(t, result.Taxes, len(records))
+			assert.Equal(t, baseline, result)
+		})
+	}
+}
+
+// calculateTaxFromCSVSequentially is the loop CalculateTaxFromCSV ran before
+// the worker pool: one record after another, appended to the response, with
+// no chunks and no cancellation. It is the reference the pool is checked and
+// benchmarked against.
+func calculateTaxFromCSVSequentially(config *domains.TaxDeductionConfig, records []schemas.CSVObjectFormat) schemas.CSVResponseV2 {
+	var response schemas.CSVResponseV2
+	for _, record := range records {
+		response.Taxes = append(response.Taxes, calculateCSVRecordTax(config, record))
+	}
+	return response
+}
+
+func TestCalculateTaxFromCSV_Cancelled(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, workers := range []int{1, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			service := NewTaxService(mockRepo, WithCSVWorkers(workers))
+			_, err := service.CalculateTaxFromCSV(ctx, generateCSVRecords(10000))
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func generateCSVRecords(n int) []schemas.CSVObjectFormat {
+	records := make([]schemas.CSVObjectFormat, n)
+	for i := range records {
+		records[i] = schemas.CSVObjectFormat{
+			TotalIncome: float64(100000 + (i%500)*10000),
+			WHT:         float64((i % 10) * 5000),
+			Allowances: []schemas.Allowance{
+				{AllowanceType: "donation", Amount: float64((i % 7) * 20000)},
+				{AllowanceType: "k-receipt", Amount: float64((i % 5) * 15000)},
+				{AllowanceType: "rmf", Amount: float64((i % 3) * 300000)},
+			},
+		}
+	}
+	return records
+}
+
+var benchmarkCSVConfig = &domains.TaxDeductionConfig{
+	PersonalDeduction:    60000,
+	DonationDeductionMax: 100000,
+	KReceiptDeductionMax: 50000,
+	RMFDeductionMax:      500000,
+}
+
+func benchmarkCalculateTaxFromCSV(b *testing.B, workers int) {
+	mockRepo := new(MockTaxRepo)
+	mockRepo.On("GetConfig").Return(benchmarkCSVConfig, nil)
+	service := NewTaxService(mockRepo, WithCSVWorkers(workers))
+	records := generateCSVRecords(100000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := service.CalculateTaxFromCSV(context.Background(), records); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCalculateTaxFromCSV_Baseline100k times the sequential loop the
+// worker pool replaced, for comparison with the benchmarks below.
+func BenchmarkCalculateTaxFromCSV_Baseline100k(b *testing.B) {
+	records := generateCSVRecords(100000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		calculateTaxFromCSVSequentially(benchmarkCSVConfig, records)
+	}
+}
+
+func BenchmarkCalculateTaxFromCSV_OneWorker100k(b *testing.B) {
+	benchmarkCalculateTaxFromCSV(b, 1)
+}
+
+func BenchmarkCalculateTaxFromCSV_Parallel100k(b *testing.B) {
+	benchmarkCalculateTaxFromCSV(b, runtime.NumCPU())
+}
+
+func TestProjectTax(t *testing.T) {
+	mockRepo := new(MockTaxRepo)
+	service := NewTaxService(mockRepo)
+	mockRepo.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		ConfigName:           "MainConfig",
+		PersonalDeduction:    60000,
+		KReceiptDeductionMax: 50000,
+		DonationDeductionMax: 100000,
+		Version:              3,
+	}, nil)
+	taxYear := 2026
+	mockRepo.On("GetTaxYearConfigs").Return([]domains.TaxDeductionConfig{{
+		ConfigName:           "TaxYear2026",
+		PersonalDeduction:    100000,
+		KReceiptDeductionMax: 50000,
+		DonationDeductionMax: 100000,
+		Version:              1,
+		TaxYear:              &taxYear,
+	}}, nil)
+
+	result, err := service.ProjectTax(context.Background(), 2025, 600000, 0.1, 0.07, []schemas.YearAllowances{
+		{Year: 2027, Allowances: []schemas.Allowance{{AllowanceType: "k-receipt", Amount: 50000}}},
+		{Year: 2025, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 40000}}},
+	})
+	assert.NoError(t, err)
+
+	type row struct {
+		year                            int
+		income, wht, grossTax           float64
+		source, configName              string
+		tax, taxRefund                  float64
+		cumulativeTax, cumulativeRefund float64
+	}
+	expected := []row{
+		{2025, 600000, 42000, 35000, schemas.ProjectionConfigLatest, "MainConfig", 0, 7000, 0, 7000},
+		{2026, 660000, 46200, 38000, schemas.ProjectionConfigTaxYear, "TaxYear2026", 0, 8200, 0, 15200},
+		{2027, 726000, 50820, 52400, schemas.ProjectionConfigLatest, "MainConfig", 1580, 0, 1580, 15200},
+		{2028, 798600, 55902, 63290, schemas.ProjectionConfigLatest, "MainConfig", 7388, 0, 8968, 15200},
+		{2029, 878460, 61492.2, 75269, schemas.ProjectionConfigLatest, "MainConfig", 13776.8, 0, 22744.8, 15200},
+	}
+	if assert.Len(t, result.Years, len(expected)) {
+		for i, want := range expected {
+			got := result.Years[i]
+			assert.Equal(t, want, row{
+				got.Year, got.Income, got.WHT, got.Result.GrossTax, got.ConfigSource, got.ConfigName,
+				got.Tax, got.TaxRefund, got.CumulativeTax, got.CumulativeTaxRefund,
+			})
+		}
+	}
+	assert.Equal(t, 3663060.0, result.TotalIncome)
+	assert.Equal(t, 22744.8, result.TotalTax)
+	assert.Equal(t, 15200.0, result.TotalTaxRefund)
+}
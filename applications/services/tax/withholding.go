@@ -0,0 +1,75 @@
+package tax
+
+import (
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"github.com/thitiphum-bluesage/assessment-tax/utilities"
+)
+
+const monthsPerYear = 12
+
+// withholdingSchedule works out PND1 withholding month by month with the
+// cumulative method. Each month the pay to date is annualized by projecting
+// the month's salary over the remaining months, and the tax on that income
+// not yet withheld is spread evenly over the remaining pay periods, so a
+// change of salary is caught up on over the rest of the year. The extra tax a
+// bonus causes is withheld in full in the month it is paid. Withholding is
+// never negative: when a pay cut leaves more withheld than the projected tax,
+// the months that follow withhold nothing and the excess is refunded at
+// filing. Otherwise the projection in December is the actual pay, so the
+// year's withholding adds up to the tax on it.
+func withholdingSchedule(monthlySalary float64, payments []schemas.MonthlyPayment, deductions float64) []schemas.WithholdingMonth {
+	byMonth := make(map[int]schemas.MonthlyPayment, len(payments))
+	for _, payment := range payments {
+		byMonth[payment.Month] = payment
+	}
+
+	annualTax := func(income float64) float64 {
+		taxableIncome := income - deductions
+		if taxableIncome < 0 {
+			taxableIncome = 0
+		}
+		return calculateProgressiveTax(taxableIncome)
+	}
+
+	schedule := make([]schemas.WithholdingMonth, monthsPerYear)
+	salary := monthlySalary
+	paidToDate, withheldToDate := 0.0, 0.0
+	for month := 1; month <= monthsPerYear; month++ {
+		payment := byMonth[month]
+		if payment.Salary != nil {
+			salary = *payment.Salary
+		}
+		remainingMonths := float64(monthsPerYear - month + 1)
+
+		projectedIncome := paidToDate + salary*remainingMonths
+		regularTax := annualTax(projectedIncome)
+		withholding := (regularTax - withheldToDate) / remainingMonths
+		if withholding < 0 {
+			withholding = 0
+		}
+
+		bonusWithholding := 0.0
+		projectedTax := regularTax
+		if payment.Bonus > 0 {
+			projectedIncome += payment.Bonus
+			projectedTax = annualTax(projectedIncome)
+			bonusWithholding = utilities.FormatToTwoDecimals(projectedTax - regularTax)
+		}
+
+		withholding = utilities.FormatToTwoDecimals(withholding + bonusWithholding)
+		paidToDate += salary + payment.Bonus
+		withheldToDate = utilities.FormatToTwoDecimals(withheldToDate + withholding)
+
+		schedule[month-1] = schemas.WithholdingMonth{
+			Month:                 month,
+			Salary:                salary,
+			Bonus:                 payment.Bonus,
+			ProjectedAnnualIncome: utilities.FormatToTwoDecimals(projectedIncome),
+			ProjectedAnnualTax:    projectedTax,
+			Withholding:           withholding,
+			BonusWithholding:      bonusWithholding,
+			WithheldToDate:        withheldToDate,
+		}
+	}
+	return schedule
+}
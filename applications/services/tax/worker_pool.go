@@ -0,0 +1,58 @@
+package tax
+
+import (
+	"context"
+	"sync"
+)
+
+// workChunkSize is the number of records a worker takes at a time. Chunks keep
+// channel overhead low on large batches while still letting cancellation be
+// noticed quickly.
+const workChunkSize = 512
+
+// runInChunks splits [0, total) into chunks and calls fn for each chunk using
+// up to workers goroutines. fn must only write to the indexes it is given, so
+// callers keep their output in input order. It stops handing out chunks once
+// ctx is done and returns ctx.Err().
+func runInChunks(ctx context.Context, total int, workers int, fn func(start, end int)) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if workers == 1 || total <= workChunkSize {
+		for start := 0; start < total; start += workChunkSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fn(start, min(start+workChunkSize, total))
+		}
+		return nil
+	}
+
+	chunks := make(chan int)
+	var cancelErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				fn(start, min(start+workChunkSize, total))
+			}
+		}()
+	}
+
+feed:
+	for start := 0; start < total; start += workChunkSize {
+		select {
+		case <-ctx.Done():
+			cancelErr = ctx.Err()
+			break feed
+		case chunks <- start:
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	return cancelErr
+}
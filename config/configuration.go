@@ -1,39 +1,234 @@
 package config
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+	DatabaseDriverMemory   = "memory"
+
+	TracesExporterNone   = "none"
+	TracesExporterOTLP   = "otlp"
+	TracesExporterStdout = "stdout"
+)
+
 type Config struct {
-	Port        string
-	DatabaseURL string
-	AdminUser   string
-	AdminPass   string
+	Port             string
+	DatabaseDriver   string
+	DatabaseURL      string
+	AdminUser        string
+	AdminPass        string
+	CSVHeaderAliases map[string]string
+	CSVWorkers       int
+	IdempotencyTTL   time.Duration
+	DBQueryTimeout   time.Duration
+	ConfigCacheTTL   time.Duration
+	AutoMigrate      bool
+	TracesExporter   string
+	ServiceName      string
+	LogLevel         string
+}
+
+// ValidationError lists every problem found in the configuration, so they can
+// all be fixed at once instead of one restart at a time.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		DatabaseDriver:   DatabaseDriverPostgres,
+		CSVHeaderAliases: map[string]string{},
+		IdempotencyTTL:   24 * time.Hour,
+		DBQueryTimeout:   5 * time.Second,
+		ConfigCacheTTL:   5 * time.Minute,
+		TracesExporter:   TracesExporterNone,
+		ServiceName:      "ktax",
+		LogLevel:         "info",
+	}
 }
 
-func GetConfig() *Config {
+// Load reads the configuration once at startup. Later sources override earlier
+// ones: built-in defaults, the YAML file named by -config or CONFIG_FILE,
+// environment variables (a .env file included) and finally command line flags.
+// Any environment variable may instead be read from the file named by the same
+// variable with a _FILE suffix, e.g. ADMIN_PASSWORD_FILE for mounted secrets.
+//
+// The arguments left after the flags, such as a subcommand, are returned.
+func Load(args []string) (*Config, []string, error) {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, loading environment variables from system")
 	}
 
-	cfg := &Config{
-		Port:        mustGetEnv("PORT"),
-		DatabaseURL: mustGetEnv("DATABASE_URL"),
-		AdminUser:   mustGetEnv("ADMIN_USERNAME"),
-		AdminPass:   mustGetEnv("ADMIN_PASSWORD"),
+	flags := flag.NewFlagSet("ktax", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path to a YAML configuration file (env CONFIG_FILE)")
+	for _, s := range settings {
+		if s.flag != "" {
+			flags.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := defaultConfig()
+	var problems []string
+
+	path := *configFile
+	if path == "" {
+		value, err := lookupEnv("CONFIG_FILE")
+		if err != nil {
+			return nil, nil, err
+		}
+		path = value
+	}
+	if path != "" {
+		if err := applyFile(cfg, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		value, err := lookupEnv(s.env)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		if err := s.set(cfg, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.env, err))
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag != "" && s.flag == f.Name {
+				if err := s.set(cfg, f.Value.String()); err != nil {
+					problems = append(problems, fmt.Sprintf("-%s: %v", s.flag, err))
+				}
+			}
+		}
+	})
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, nil, &ValidationError{Problems: problems}
+	}
+	return cfg, flags.Args(), nil
+}
+
+func (cfg *Config) validate() []string {
+	var problems []string
+
+	if cfg.Port == "" {
+		problems = append(problems, "PORT is required")
+	} else if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT %q must be a number between 1 and 65535", cfg.Port))
+	}
+
+	switch cfg.DatabaseDriver {
+	case DatabaseDriverPostgres, DatabaseDriverSQLite:
+		if cfg.DatabaseURL == "" {
+			problems = append(problems, fmt.Sprintf("DATABASE_URL is required for the %s driver", cfg.DatabaseDriver))
+		}
+	case DatabaseDriverMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("DATABASE_DRIVER %q is not supported, expected postgres, sqlite or memory", cfg.DatabaseDriver))
+	}
+
+	if cfg.AdminUser == "" {
+		problems = append(problems, "ADMIN_USERNAME is required")
+	}
+	if cfg.AdminPass == "" {
+		problems = append(problems, "ADMIN_PASSWORD is required")
+	}
+
+	if cfg.CSVWorkers < 0 {
+		problems = append(problems, "CSV_WORKERS must not be negative")
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		problems = append(problems, "IDEMPOTENCY_TTL_HOURS must be positive")
+	}
+	if cfg.DBQueryTimeout < 0 {
+		problems = append(problems, "DATABASE_QUERY_TIMEOUT_MS must not be negative")
+	}
+	if cfg.ConfigCacheTTL < 0 {
+		problems = append(problems, "CONFIG_CACHE_TTL_SECONDS must not be negative")
+	}
+
+	switch cfg.TracesExporter {
+	case TracesExporterNone, TracesExporterOTLP, TracesExporterStdout:
+	default:
+		problems = append(problems, fmt.Sprintf("OTEL_TRACES_EXPORTER %q is not supported, expected none, otlp or stdout", cfg.TracesExporter))
 	}
+	if cfg.ServiceName == "" {
+		problems = append(problems, "OTEL_SERVICE_NAME must not be empty")
+	}
+
+	switch strings.ToLower(cfg.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q is not supported, expected debug, info, warn or error", cfg.LogLevel))
+	}
+
+	return problems
+}
+
+// lookupEnv returns the value of key, or the contents of the file named by
+// key_FILE without its trailing newline. Setting both is an error, since it is
+// unclear which one was meant.
+func lookupEnv(key string) (string, error) {
+	value := os.Getenv(key)
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("%s and %s_FILE are both set", key, key)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
 
-	return cfg
+// parseCSVHeaderAliases reads extra CSV header aliases in the form
+// "alias=column;alias=column", e.g. "salary=totalincome;tax paid=wht".
+func parseCSVHeaderAliases(value string) map[string]string {
+	aliases := make(map[string]string)
+	for _, pair := range strings.Split(value, ";") {
+		alias, column, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		addCSVHeaderAlias(aliases, alias, column)
+	}
+	return aliases
 }
 
-func mustGetEnv(key string) string {
-	if value, exists := os.LookupEnv(key); !exists {
-		log.Fatalf("Required environment variable %s not set", key)
-	} else {
-		return value
+func addCSVHeaderAlias(aliases map[string]string, alias, column string) {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	column = strings.ToLower(strings.TrimSpace(column))
+	if alias == "" || column == "" {
+		return
 	}
-	return ""
+	aliases[alias] = column
 }
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv blanks every variable Load reads, so the tests do not depend on the
+// environment they run in.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("CONFIG_FILE_FILE", "")
+	for _, s := range settings {
+		t.Setenv(s.env, "")
+		t.Setenv(s.env+"_FILE", "")
+	}
+}
+
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("PORT", "8080")
+	t.Setenv("DATABASE_URL", "postgres://localhost/ktax")
+	t.Setenv("ADMIN_USERNAME", "adminTax")
+	t.Setenv("ADMIN_PASSWORD", "admin!")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+
+	cfg, args, err := Load(nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, args)
+	assert.Equal(t, "8080", cfg.Port)
+	assert.Equal(t, DatabaseDriverPostgres, cfg.DatabaseDriver)
+	assert.Equal(t, 24*time.Hour, cfg.IdempotencyTTL)
+	assert.Equal(t, 5*time.Second, cfg.DBQueryTimeout)
+	assert.Equal(t, 5*time.Minute, cfg.ConfigCacheTTL)
+	assert.Equal(t, TracesExporterNone, cfg.TracesExporter)
+	assert.Equal(t, "ktax", cfg.ServiceName)
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.False(t, cfg.AutoMigrate)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+	path := writeFile(t, "ktax.yaml", `
+port: "7000"
+log:
+  level: debug
+database:
+  queryTimeout: 2s
+csv:
+  workers: 2
+  headerAliases:
+    Salary: TotalIncome
+tracing:
+  serviceName: from-file
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("CSV_WORKERS", "4")
+	t.Setenv("LOG_LEVEL", "warn")
+
+	cfg, args, err := Load([]string{"-log-level", "error", "migrate", "up"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"migrate", "up"}, args)
+	assert.Equal(t, "8080", cfg.Port, "env overrides file")
+	assert.Equal(t, 4, cfg.CSVWorkers, "env overrides file")
+	assert.Equal(t, "error", cfg.LogLevel, "flag overrides env")
+	assert.Equal(t, 2*time.Second, cfg.DBQueryTimeout, "file overrides default")
+	assert.Equal(t, "from-file", cfg.ServiceName)
+	assert.Equal(t, map[string]string{"salary": "totalincome"}, cfg.CSVHeaderAliases)
+}
+
+func TestLoad_ConfigFlagWinsOverEnv(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "env.yaml", "tracing:\n  serviceName: from-env-file\n"))
+	flagFile := writeFile(t, "flag.yaml", "tracing:\n  serviceName: from-flag-file\n")
+
+	cfg, _, err := Load([]string{"-config", flagFile})
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-flag-file", cfg.ServiceName)
+}
+
+func TestLoad_FileRejectsUnknownKeys(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+
+	_, _, err := Load([]string{"-config", writeFile(t, "ktax.yaml", "prot: 8080\n")})
+
+	assert.ErrorContains(t, err, "prot")
+}
+
+func TestLoad_SecretFromFile(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+	t.Setenv("ADMIN_PASSWORD", "")
+	t.Setenv("ADMIN_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+
+	cfg, _, err := Load(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "s3cret", cfg.AdminPass)
+}
+
+func TestLoad_SecretSetTwice(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+	t.Setenv("ADMIN_PASSWORD_FILE", writeFile(t, "password", "s3cret"))
+
+	_, _, err := Load(nil)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.Problems, "ADMIN_PASSWORD and ADMIN_PASSWORD_FILE are both set")
+}
+
+func TestLoad_AggregatesProblems(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PORT", "99999")
+	t.Setenv("CSV_WORKERS", "many")
+	t.Setenv("OTEL_TRACES_EXPORTER", "jaeger")
+
+	_, _, err := Load(nil)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{
+		`CSV_WORKERS: invalid integer "many"`,
+		`PORT "99999" must be a number between 1 and 65535`,
+		"DATABASE_URL is required for the postgres driver",
+		"ADMIN_USERNAME is required",
+		"ADMIN_PASSWORD is required",
+		`OTEL_TRACES_EXPORTER "jaeger" is not supported, expected none, otlp or stdout`,
+	}, validationErr.Problems)
+}
+
+func TestLoad_MemoryDriverNeedsNoURL(t *testing.T) {
+	clearEnv(t)
+	setRequiredEnv(t)
+	t.Setenv("DATABASE_URL", "")
+
+	cfg, _, err := Load([]string{"-database-driver", DatabaseDriverMemory})
+	require.NoError(t, err)
+
+	assert.Equal(t, DatabaseDriverMemory, cfg.DatabaseDriver)
+}
+
+func TestLoad_Help(t *testing.T) {
+	clearEnv(t)
+
+	_, _, err := Load([]string{"-h"})
+
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a configuration value that can be set from an environment
+// variable and, unless it is a secret, a command line flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(cfg *Config, value string) error
+}
+
+var settings = []setting{
+	{"PORT", "port", "HTTP port to listen on", func(cfg *Config, value string) error {
+		cfg.Port = value
+		return nil
+	}},
+	{"DATABASE_DRIVER", "database-driver", "storage backend: postgres, sqlite or memory", func(cfg *Config, value string) error {
+		cfg.DatabaseDriver = value
+		return nil
+	}},
+	{"DATABASE_URL", "", "", func(cfg *Config, value string) error {
+		cfg.DatabaseURL = value
+		return nil
+	}},
+	{"ADMIN_USERNAME", "", "", func(cfg *Config, value string) error {
+		cfg.AdminUser = value
+		return nil
+	}},
+	{"ADMIN_PASSWORD", "", "", func(cfg *Config, value string) error {
+		cfg.AdminPass = value
+		return nil
+	}},
+	{"CSV_HEADER_ALIASES", "csv-header-aliases", `extra CSV header aliases, e.g. "salary=totalincome;tax paid=wht"`, func(cfg *Config, value string) error {
+		for alias, column := range parseCSVHeaderAliases(value) {
+			cfg.CSVHeaderAliases[alias] = column
+		}
+		return nil
+	}},
+	{"CSV_WORKERS", "csv-workers", "number of workers calculating CSV rows, 0 for one per CPU", intSetter(func(cfg *Config) *int { return &cfg.CSVWorkers })},
+	{"IDEMPOTENCY_TTL_HOURS", "idempotency-ttl-hours", "how long idempotent responses are kept", durationSetter(func(cfg *Config) *time.Duration { return &cfg.IdempotencyTTL }, time.Hour)},
+	{"DATABASE_QUERY_TIMEOUT_MS", "database-query-timeout-ms", "upper bound for a single database query", durationSetter(func(cfg *Config) *time.Duration { return &cfg.DBQueryTimeout }, time.Millisecond)},
+	{"CONFIG_CACHE_TTL_SECONDS", "config-cache-ttl-seconds", "how long the deduction configuration is cached, 0 to disable", durationSetter(func(cfg *Config) *time.Duration { return &cfg.ConfigCacheTTL }, time.Second)},
+	{"DATABASE_AUTO_MIGRATE", "database-auto-migrate", "apply pending migrations at startup", func(cfg *Config, value string) error {
+		autoMigrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		cfg.AutoMigrate = autoMigrate
+		return nil
+	}},
+	{"OTEL_TRACES_EXPORTER", "traces-exporter", "where to send traces: otlp, stdout or none", func(cfg *Config, value string) error {
+		cfg.TracesExporter = value
+		return nil
+	}},
+	{"OTEL_SERVICE_NAME", "service-name", "service name attached to traces", func(cfg *Config, value string) error {
+		cfg.ServiceName = value
+		return nil
+	}},
+	{"LOG_LEVEL", "log-level", "minimum log level: debug, info, warn or error", func(cfg *Config, value string) error {
+		cfg.LogLevel = value
+		return nil
+	}},
+}
+
+func intSetter(field func(cfg *Config) *int) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(cfg) = number
+		return nil
+	}
+}
+
+// durationSetter parses a whole number of unit, matching the unit in the
+// variable name, e.g. hours for IDEMPOTENCY_TTL_HOURS.
+func durationSetter(field func(cfg *Config) *time.Duration, unit time.Duration) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(cfg) = time.Duration(number) * unit
+		return nil
+	}
+}
+
+// fileConfig is the layout of the YAML configuration file. Durations are
+// written as Go durations, e.g. "24h" or "500ms". Fields left out of the file
+// keep their defaults.
+type fileConfig struct {
+	Port     *string `yaml:"port"`
+	Database struct {
+		Driver       *string        `yaml:"driver"`
+		URL          *string        `yaml:"url"`
+		QueryTimeout *time.Duration `yaml:"queryTimeout"`
+		AutoMigrate  *bool          `yaml:"autoMigrate"`
+	} `yaml:"database"`
+	Admin struct {
+		Username *string `yaml:"username"`
+		Password *string `yaml:"password"`
+	} `yaml:"admin"`
+	CSV struct {
+		Workers       *int              `yaml:"workers"`
+		HeaderAliases map[string]string `yaml:"headerAliases"`
+	} `yaml:"csv"`
+	Idempotency struct {
+		TTL *time.Duration `yaml:"ttl"`
+	} `yaml:"idempotency"`
+	ConfigCache struct {
+		TTL *time.Duration `yaml:"ttl"`
+	} `yaml:"configCache"`
+	Tracing struct {
+		Exporter    *string `yaml:"exporter"`
+		ServiceName *string `yaml:"serviceName"`
+	} `yaml:"tracing"`
+	Log struct {
+		Level *string `yaml:"level"`
+	} `yaml:"log"`
+}
+
+// applyFile overrides cfg with every value set in the YAML file at path.
+// Unknown keys are rejected so that typos do not go unnoticed.
+func applyFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open configuration file: %w", err)
+	}
+	defer file.Close()
+
+	var fc fileConfig
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read configuration file %s: %w", path, err)
+	}
+
+	setIfPresent(&cfg.Port, fc.Port)
+	setIfPresent(&cfg.DatabaseDriver, fc.Database.Driver)
+	setIfPresent(&cfg.DatabaseURL, fc.Database.URL)
+	setIfPresent(&cfg.DBQueryTimeout, fc.Database.QueryTimeout)
+	setIfPresent(&cfg.AutoMigrate, fc.Database.AutoMigrate)
+	setIfPresent(&cfg.AdminUser, fc.Admin.Username)
+	setIfPresent(&cfg.AdminPass, fc.Admin.Password)
+	setIfPresent(&cfg.CSVWorkers, fc.CSV.Workers)
+	for alias, column := range fc.CSV.HeaderAliases {
+		addCSVHeaderAlias(cfg.CSVHeaderAliases, alias, column)
+	}
+	setIfPresent(&cfg.IdempotencyTTL, fc.Idempotency.TTL)
+	setIfPresent(&cfg.ConfigCacheTTL, fc.ConfigCache.TTL)
+	setIfPresent(&cfg.TracesExporter, fc.Tracing.Exporter)
+	setIfPresent(&cfg.ServiceName, fc.Tracing.ServiceName)
+	setIfPresent(&cfg.LogLevel, fc.Log.Level)
+	return nil
+}
+
+func setIfPresent[T any](field *T, value *T) {
+	if value != nil {
+		*field = *value
+	}
+}
@@ -18,6 +18,43 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/deductions": {
+            "get": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Get the current deduction limits. The ETag response header carries the configuration version to send as If-Match when updating.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get deduction configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.DeductionConfigResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Configuration version"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/deductions/k-receipt": {
             "post": {
                 "security": [
@@ -25,7 +62,7 @@ const docTemplate = `{
                         "basicAuth": []
                     }
                 ],
-                "description": "Update the K receipt deduction for a tax payer",
+                "description": "Update the K receipt deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.",
                 "consumes": [
                     "application/json"
                 ],
@@ -37,6 +74,13 @@ const docTemplate = `{
                 ],
                 "summary": "Update K receipt deduction",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "description": "Update K Receipt Deduction Request",
                         "name": "request",
@@ -52,6 +96,12 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/schemas.UpdateKReceiptResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
                         }
                     },
                     "400": {
@@ -60,6 +110,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/schemas.ErrorResponse"
                         }
                     },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -76,7 +138,7 @@ const docTemplate = `{
                         "basicAuth": []
                     }
                 ],
-                "description": "Update the personal deduction for a tax payer",
+                "description": "Update the personal deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.",
                 "consumes": [
                     "application/json"
                 ],
@@ -88,6 +150,13 @@ const docTemplate = `{
                 ],
                 "summary": "Update personal deduction",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "description": "Update Personal Deduction Request",
                         "name": "request",
@@ -103,6 +172,12 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/schemas.UpdatePersonalDeductionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
                         }
                     },
                     "400": {
@@ -111,6 +186,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/schemas.ErrorResponse"
                         }
                     },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -120,6 +207,52 @@ const docTemplate = `{
                 }
             }
         },
+        "/healthz": {
+            "get": {
+                "description": "Always succeeds while the server can handle requests. It does not check dependencies, so a database outage does not get the process restarted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Runs every readiness check, such as database connectivity, migration status and the presence of the deduction configuration, and reports the status and latency of each.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "At least one check failed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/tax/calculations": {
             "post": {
                 "description": "Calculates taxes including breakdowns by tax level and potential refunds.",
@@ -133,6 +266,7 @@ const docTemplate = `{
                     "tax"
                 ],
                 "summary": "Calculate detailed tax",
+                "deprecated": true,
                 "parameters": [
                     {
                         "description": "Tax Calculation Request",
@@ -142,6 +276,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/schemas.TaxCalculationRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -166,9 +306,61 @@ const docTemplate = `{
                 }
             }
         },
+        "/tax/calculations/batch": {
+            "post": {
+                "description": "Accepts one tax calculation request per line (NDJSON / JSON Lines) and streams back one detailed result per line in the same order. Lines that cannot be parsed, validated or calculated produce a result with an error message instead of failing the whole batch. A stream canceled part way ends with a line whose errorCode is canceled.",
+                "consumes": [
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate taxes from NDJSON",
+                "parameters": [
+                    {
+                        "description": "One Tax Calculation Request per line",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxCalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries return the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One result per input line",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.BatchTaxCalculationResult"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different payload",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/tax/calculations/upload-csv": {
             "post": {
-                "description": "Accepts a file upload (CSV format) with tax data, processes each record, and returns tax calculations.",
+                "description": "Accepts a file upload (CSV format) with tax data, processes each record, and returns tax calculations. Comma, semicolon and tab delimited files in UTF-8 (with or without BOM) or TIS-620 are accepted, headers may use English or Thai aliases, and numbers may contain thousands separators.",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -186,6 +378,12 @@ const docTemplate = `{
                         "name": "taxFile",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries return the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -201,6 +399,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/schemas.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different file",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -209,95 +419,2463 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "schemas.Allowance": {
-            "type": "object",
-            "properties": {
-                "allowanceType": {
-                    "type": "string"
-                },
-                "amount": {
-                    "type": "number"
-                }
-            }
         },
-        "schemas.CSVResponse": {
-            "type": "object",
-            "properties": {
-                "taxes": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/schemas.CSVResponseMember"
+        "/tax/calculations/upload-csv/validate": {
+            "post": {
+                "description": "Dry run for the CSV upload: checks headers, number formats and record values, and reports every problem with its row and column. No taxes are calculated and nothing is stored. Row 0 refers to the header line.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Validate a tax CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file containing tax data",
+                        "name": "taxFile",
+                        "in": "formData",
+                        "required": true
                     }
-                }
-            }
-        },
-        "schemas.CSVResponseMember": {
-            "type": "object",
-            "properties": {
-                "tax": {
-                    "type": "number"
-                },
-                "taxRefund": {
-                    "type": "number"
-                },
-                "totalIncome": {
-                    "type": "number"
-                }
-            }
-        },
-        "schemas.DetailedTaxCalculationResponse": {
-            "type": "object",
-            "properties": {
-                "tax": {
-                    "type": "number"
-                },
-                "taxLevel": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/schemas.TaxLevel"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Validation report for the uploaded CSV",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.CSVValidationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "File missing or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "schemas.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                }
-            }
-        },
-        "schemas.TaxCalculationRequest": {
-            "type": "object",
-            "properties": {
-                "allowances": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/schemas.Allowance"
+        "/v1/admin/deductions": {
+            "get": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Get the current deduction limits. The ETag response header carries the configuration version to send as If-Match when updating.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get deduction configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.DeductionConfigResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Configuration version"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
                     }
-                },
-                "totalIncome": {
-                    "type": "number"
-                },
-                "wht": {
-                    "type": "number"
                 }
             }
         },
-        "schemas.TaxLevel": {
-            "type": "object",
-            "properties": {
-                "level": {
-                    "type": "string"
-                },
+        "/v1/admin/deductions/k-receipt": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the K receipt deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update K receipt deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update K Receipt Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateKReceiptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateKReceiptResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/deductions/personal": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the personal deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update personal deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Personal Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdatePersonalDeductionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdatePersonalDeductionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tax/calculations": {
+            "post": {
+                "description": "Calculates taxes including breakdowns by tax level and potential refunds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate detailed tax",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Tax Calculation Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxCalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Detailed breakdown of tax calculations",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.DetailedTaxCalculationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tax/calculations/batch": {
+            "post": {
+                "description": "Accepts one tax calculation request per line (NDJSON / JSON Lines) and streams back one detailed result per line in the same order. Lines that cannot be parsed, validated or calculated produce a result with an error message instead of failing the whole batch. A stream canceled part way ends with a line whose errorCode is canceled.",
+                "consumes": [
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate taxes from NDJSON",
+                "parameters": [
+                    {
+                        "description": "One Tax Calculation Request per line",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxCalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries return the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One result per input line",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.BatchTaxCalculationResult"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different payload",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tax/calculations/upload-csv": {
+            "post": {
+                "description": "Accepts a file upload (CSV format) with tax data, processes each record, and returns tax calculations. Comma, semicolon and tab delimited files in UTF-8 (with or without BOM) or TIS-620 are accepted, headers may use English or Thai aliases, and numbers may contain thousands separators.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate taxes from CSV",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file containing tax data",
+                        "name": "taxFile",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries return the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tax calculations for all records in the uploaded CSV",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.CSVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or CSV format errors",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different file",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tax/calculations/upload-csv/validate": {
+            "post": {
+                "description": "Dry run for the CSV upload: checks headers, number formats and record values, and reports every problem with its row and column. No taxes are calculated and nothing is stored. Row 0 refers to the header line.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Validate a tax CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file containing tax data",
+                        "name": "taxFile",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Validation report for the uploaded CSV",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.CSVValidationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "File missing or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions": {
+            "get": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Get the current deduction limits, including the insurance, RMF and SSF caps. The ETag response header carries the configuration version to send as If-Match when updating.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get deduction configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.DeductionConfigV2Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Configuration version"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/insurance": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the insurance deduction limit. If-Match must carry the ETag from GET /v2/admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update insurance deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Insurance Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateInsuranceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateInsuranceResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/k-receipt": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the K receipt deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update K receipt deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update K Receipt Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateKReceiptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateKReceiptResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/personal": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the personal deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update personal deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Personal Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdatePersonalDeductionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdatePersonalDeductionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/rmf": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the RMF (Retirement Mutual Fund) deduction limit. If-Match must carry the ETag from GET /v2/admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update RMF deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update RMF Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateRMFRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateRMFResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/ssf": {
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Update the SSF (Super Savings Fund) deduction limit. If-Match must carry the ETag from GET /v2/admin/deductions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update SSF deduction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update SSF Deduction Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateSSFRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.UpdateSSFResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/tax-years": {
+            "get": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "List the deduction configurations pinned to a tax year, ordered by year. Projections use them for the years they cover.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List tax year deduction configurations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxYearConfigsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/admin/deductions/tax-years/{year}": {
+            "put": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Replace every limit of the deduction configuration of a tax year. Limits that are left out take their default. If-Match must carry the ETag from creating the configuration or from the last update.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update tax year deduction configuration",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the configuration being updated, or * for the current version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Tax Year Configuration Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxYearConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxYearConfigResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "The tax year has no configuration",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Configuration was changed by another request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is missing",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "basicAuth": []
+                    }
+                ],
+                "description": "Create the deduction configuration of a tax year at version 1. Limits that are left out take their default. The ETag response header carries the version to send as If-Match when updating.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create tax year deduction configuration",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tax Year Configuration Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxYearConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxYearConfigResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Configuration version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The tax year already has a configuration",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/calculations": {
+            "post": {
+                "description": "Calculates tax and always returns the tax, refund, taxable income, total deductions, gross tax, WHT credited and net tax, every deduction line with the amount claimed and allowed, the cap applied and why, and every bracket with its bounds and rate.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate tax (v2)",
+                "parameters": [
+                    {
+                        "description": "Tax Calculation Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxCalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels, deduction explanations and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tax calculation with deductions and brackets",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxCalculationV2Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/calculations/batch": {
+            "post": {
+                "description": "Accepts one tax calculation request per line (NDJSON / JSON Lines) and streams back one detailed result per line in the same order. Lines that cannot be parsed, validated or calculated produce a result with an error message instead of failing the whole batch. A stream canceled part way ends with a line whose errorCode is canceled.",
+                "consumes": [
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate taxes from NDJSON",
+                "parameters": [
+                    {
+                        "description": "One Tax Calculation Request per line",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.TaxCalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries return the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One result per input line",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.BatchTaxCalculationResult"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different payload",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/calculations/upload-csv": {
+            "post": {
+                "description": "Same as the v1 CSV upload, and every record also carries its marginal rate, effective rate and distance to the next bracket.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate taxes from CSV",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file containing tax data",
+                        "name": "taxFile",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries return the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tax calculations for all records in the uploaded CSV",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.CSVResponseV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or CSV format errors",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different file",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/calculations/upload-csv/validate": {
+            "post": {
+                "description": "Dry run for the CSV upload: checks headers, number formats and record values, and reports every problem with its row and column. No taxes are calculated and nothing is stored. Row 0 refers to the header line.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Validate a tax CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file containing tax data",
+                        "name": "taxFile",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Validation report for the uploaded CSV",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.CSVValidationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "File missing or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/deductions/optimize": {
+            "post": {
+                "description": "Allocates a budget to the room left under the configured donation and k-receipt caps, on top of the allowances already claimed, to give the lowest tax. items limits and orders the allowance types the budget goes to; insurance, rmf and ssf have no cap yet and are rejected with not_optimizable. Returns the allocation, the tax saved per baht spent, the saturation point past which spending no longer reduces tax, and the v2 calculation with the allocation applied.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Optimize deductions",
+                "parameters": [
+                    {
+                        "description": "Tax calculation request with a budget",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.DeductionOptimizationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels, deduction explanations and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Allocation with the lowest tax",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.DeductionOptimizationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/gross-up": {
+            "post": {
+                "description": "Solves for the gross income whose take-home pay after personal income tax equals targetNetIncome, given the allowances, and returns it with the tax and bracket breakdown. whtPolicy decides how the tax is settled: full withholds all of it (default), none withholds nothing, and rate withholds whtRate of the gross income.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Gross up a net income",
+                "parameters": [
+                    {
+                        "description": "Gross-up Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.GrossUpRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels, deduction explanations and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Gross income with its tax calculation",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.GrossUpResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/projections": {
+            "post": {
+                "description": "Calculates five tax years from startYear, which defaults to the current year. Income starts at startingIncome and grows by growthRate a year, and whtRate of each year's income is withheld. The plan lists allowances by year; a year without an entry keeps the allowances of the year before. Each year is calculated with the deduction configuration for its tax year when one exists and with the latest configuration (MainConfig) otherwise. Returns a yearly table with cumulative tax and refund, and the totals.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Project tax over five years",
+                "parameters": [
+                    {
+                        "description": "Start year, starting income, growth rate, WHT rate and allowance plan",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ProjectionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels, deduction explanations and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Yearly and cumulative tax and refund",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ProjectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/scenarios/compare": {
+            "post": {
+                "description": "Calculates a base tax calculation request and up to 20 named variants with the same deduction configuration, and returns each v2 calculation with its difference from the base: tax, refund, taxable income, deductions and the tax of every bracket.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Compare tax scenarios",
+                "parameters": [
+                    {
+                        "description": "Base request and named variants",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ScenarioComparisonRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels, deduction explanations and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Calculations with their differences from the base",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ScenarioComparisonResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tax/withholding": {
+            "post": {
+                "description": "Returns a 12-month withholding schedule. Each month annualizes the pay to date with the month's salary projected over the remaining months, and spreads the tax not yet withheld over the remaining pay periods. The extra tax caused by a bonus is withheld in the month it is paid. The same allowances and brackets as the annual calculation are used, so the year's withholding adds up to the annual tax.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate monthly withholding (PND1)",
+                "parameters": [
+                    {
+                        "description": "Monthly salary, payments by month and allowances",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.WithholdingRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "th or en; language of tax level labels, deduction explanations and error messages",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "12-month withholding schedule",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.WithholdingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "schemas.Allowance": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.BatchTaxCalculationResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errorCode": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.FieldError"
+                    }
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.TaxLevel"
+                    }
+                },
+                "taxRefund": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.CSVResponse": {
+            "type": "object",
+            "properties": {
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.CSVResponseMember"
+                    }
+                }
+            }
+        },
+        "schemas.CSVResponseMember": {
+            "type": "object",
+            "properties": {
+                "tax": {
+                    "type": "number"
+                },
+                "taxRefund": {
+                    "type": "number"
+                },
+                "totalIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.CSVResponseV2": {
+            "type": "object",
+            "properties": {
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.CSVResponseV2Member"
+                    }
+                }
+            }
+        },
+        "schemas.CSVResponseV2Member": {
+            "type": "object",
+            "properties": {
+                "distanceToNextBracket": {
+                    "type": "number",
+                    "example": 160000
+                },
+                "effectiveRate": {
+                    "type": "number",
+                    "example": 0.038
+                },
+                "marginalRate": {
+                    "type": "number",
+                    "example": 0.1
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "taxRefund": {
+                    "type": "number"
+                },
+                "totalIncome": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.CSVValidationProblem": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "type": "string",
+                    "example": "wht"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "invalid number"
+                },
+                "row": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "value": {
+                    "type": "string",
+                    "example": "abc"
+                }
+            }
+        },
+        "schemas.CSVValidationReport": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "problems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.CSVValidationProblem"
+                    }
+                },
+                "rowCount": {
+                    "type": "integer"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "schemas.DeductionAllocation": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string",
+                    "example": "donation"
+                },
+                "amount": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "room": {
+                    "type": "number",
+                    "example": 100000
+                }
+            }
+        },
+        "schemas.DeductionConfigResponse": {
+            "type": "object",
+            "properties": {
+                "donation": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceipt": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "personalDeduction": {
+                    "type": "number",
+                    "example": 60000
+                }
+            }
+        },
+        "schemas.DeductionConfigV2Response": {
+            "type": "object",
+            "properties": {
+                "donation": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "insurance": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceipt": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "personalDeduction": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "rmf": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "ssf": {
+                    "type": "number",
+                    "example": 200000
+                }
+            }
+        },
+        "schemas.DeductionLine": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string",
+                    "example": "donation"
+                },
+                "allowed": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "cap": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "claimed": {
+                    "type": "number",
+                    "example": 200000
+                },
+                "explanation": {
+                    "type": "string",
+                    "example": "Capped at the maximum of 100,000"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "capped"
+                }
+            }
+        },
+        "schemas.DeductionOptimizationRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.Allowance"
+                    }
+                },
+                "budget": {
+                    "type": "number",
+                    "example": 200000
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "donation",
+                        "k-receipt"
+                    ]
+                },
+                "totalIncome": {
+                    "type": "number"
+                },
+                "wht": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.DeductionOptimizationResponse": {
+            "type": "object",
+            "properties": {
+                "allocation": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.DeductionAllocation"
+                    }
+                },
+                "budget": {
+                    "type": "number",
+                    "example": 200000
+                },
+                "result": {
+                    "$ref": "#/definitions/schemas.TaxCalculationV2Response"
+                },
+                "saturationPoint": {
+                    "type": "number",
+                    "example": 150000
+                },
+                "spent": {
+                    "type": "number",
+                    "example": 150000
+                },
+                "taxAfter": {
+                    "type": "number",
+                    "example": 14000
+                },
+                "taxBefore": {
+                    "type": "number",
+                    "example": 29000
+                },
+                "taxSaved": {
+                    "type": "number",
+                    "example": 15000
+                },
+                "taxSavedPerBaht": {
+                    "type": "number",
+                    "example": 0.1
+                },
+                "unspent": {
+                    "type": "number",
+                    "example": 50000
+                }
+            }
+        },
+        "schemas.DetailedTaxCalculationResponse": {
+            "type": "object",
+            "properties": {
+                "tax": {
+                    "type": "number"
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.TaxLevel"
+                    }
+                }
+            }
+        },
+        "schemas.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "validation_failed"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "validation errors: WHT cannot be greater than TotalIncome"
+                }
+            }
+        },
+        "schemas.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "must_be_non_negative"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "allowances[1].amount"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Amount for donation must be non-negative"
+                },
+                "row": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "value": {
+                    "type": "string",
+                    "example": "-500"
+                }
+            }
+        },
+        "schemas.GrossUpRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.Allowance"
+                    }
+                },
+                "targetNetIncome": {
+                    "type": "number",
+                    "example": 400000
+                },
+                "whtPolicy": {
+                    "type": "string",
+                    "enum": [
+                        "full",
+                        "none",
+                        "rate"
+                    ],
+                    "example": "full"
+                },
+                "whtRate": {
+                    "type": "number",
+                    "example": 0.03
+                }
+            }
+        },
+        "schemas.GrossUpResponse": {
+            "type": "object",
+            "properties": {
+                "deductions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.DeductionLine"
+                    }
+                },
+                "distanceToNextBracket": {
+                    "type": "number",
+                    "example": 160000
+                },
+                "effectiveRate": {
+                    "type": "number",
+                    "example": 0.038
+                },
+                "grossIncome": {
+                    "type": "number",
+                    "example": 431111.11
+                },
+                "grossTax": {
+                    "type": "number",
+                    "example": 19000
+                },
+                "marginalRate": {
+                    "type": "number",
+                    "example": 0.1
+                },
+                "netIncome": {
+                    "type": "number",
+                    "example": 400000
+                },
+                "netTax": {
+                    "type": "number",
+                    "example": -31000
+                },
+                "targetNetIncome": {
+                    "type": "number",
+                    "example": 400000
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 0
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.TaxBracket"
+                    }
+                },
+                "taxRefund": {
+                    "type": "number",
+                    "example": 31000
+                },
+                "taxableIncome": {
+                    "type": "number",
+                    "example": 340000
+                },
+                "totalDeductions": {
+                    "type": "number",
+                    "example": 160000
+                },
+                "whtCredited": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "whtPolicy": {
+                    "type": "string",
+                    "example": "full"
+                }
+            }
+        },
+        "schemas.HealthCheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latencyMs": {
+                    "type": "number",
+                    "example": 1.25
+                },
+                "name": {
+                    "type": "string",
+                    "example": "database"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "schemas.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.HealthCheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "schemas.MonthlyPayment": {
+            "type": "object",
+            "properties": {
+                "bonus": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "month": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "salary": {
+                    "type": "number",
+                    "example": 55000
+                }
+            }
+        },
+        "schemas.ProjectionRequest": {
+            "type": "object",
+            "properties": {
+                "growthRate": {
+                    "type": "number",
+                    "example": 0.05
+                },
+                "plan": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.YearAllowances"
+                    }
+                },
+                "startYear": {
+                    "type": "integer",
+                    "example": 2025
+                },
+                "startingIncome": {
+                    "type": "number",
+                    "example": 600000
+                },
+                "whtRate": {
+                    "type": "number",
+                    "example": 0.05
+                }
+            }
+        },
+        "schemas.ProjectionResponse": {
+            "type": "object",
+            "properties": {
+                "totalIncome": {
+                    "type": "number",
+                    "example": 3315378.75
+                },
+                "totalTax": {
+                    "type": "number",
+                    "example": 56537.87
+                },
+                "totalTaxRefund": {
+                    "type": "number",
+                    "example": 0
+                },
+                "years": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.ProjectionYear"
+                    }
+                }
+            }
+        },
+        "schemas.ProjectionYear": {
+            "type": "object",
+            "properties": {
+                "configName": {
+                    "type": "string",
+                    "example": "MainConfig"
+                },
+                "configSource": {
+                    "type": "string",
+                    "enum": [
+                        "tax_year",
+                        "latest"
+                    ],
+                    "example": "latest"
+                },
+                "configVersion": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "cumulativeTax": {
+                    "type": "number",
+                    "example": 5000
+                },
+                "cumulativeTaxRefund": {
+                    "type": "number",
+                    "example": 0
+                },
+                "income": {
+                    "type": "number",
+                    "example": 600000
+                },
+                "result": {
+                    "$ref": "#/definitions/schemas.TaxCalculationV2Response"
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 5000
+                },
+                "taxRefund": {
+                    "type": "number",
+                    "example": 0
+                },
+                "wht": {
+                    "type": "number",
+                    "example": 30000
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2025
+                }
+            }
+        },
+        "schemas.ScenarioComparisonRequest": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "$ref": "#/definitions/schemas.TaxCalculationRequest"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.ScenarioVariant"
+                    }
+                }
+            }
+        },
+        "schemas.ScenarioComparisonResponse": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "$ref": "#/definitions/schemas.ScenarioResult"
+                },
+                "configVersion": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.ScenarioResult"
+                    }
+                }
+            }
+        },
+        "schemas.ScenarioDifference": {
+            "type": "object",
+            "properties": {
+                "grossTax": {
+                    "type": "number",
+                    "example": -4000
+                },
+                "tax": {
+                    "type": "number",
+                    "example": -4000
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.TaxLevel"
+                    }
+                },
+                "taxRefund": {
+                    "type": "number",
+                    "example": 0
+                },
+                "taxableIncome": {
+                    "type": "number",
+                    "example": -40000
+                },
+                "totalDeductions": {
+                    "type": "number",
+                    "example": 40000
+                }
+            }
+        },
+        "schemas.ScenarioResult": {
+            "type": "object",
+            "properties": {
+                "difference": {
+                    "$ref": "#/definitions/schemas.ScenarioDifference"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "max donation"
+                },
+                "result": {
+                    "$ref": "#/definitions/schemas.TaxCalculationV2Response"
+                }
+            }
+        },
+        "schemas.ScenarioVariant": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.Allowance"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "max donation"
+                },
+                "totalIncome": {
+                    "type": "number"
+                },
+                "wht": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.TaxBracket": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "150,001-500,000"
+                },
+                "lowerBound": {
+                    "type": "number",
+                    "example": 150000
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 0.1
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 29000
+                },
+                "upperBound": {
+                    "type": "number",
+                    "example": 500000
+                }
+            }
+        },
+        "schemas.TaxCalculationRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.Allowance"
+                    }
+                },
+                "totalIncome": {
+                    "type": "number"
+                },
+                "wht": {
+                    "type": "number"
+                }
+            }
+        },
+        "schemas.TaxCalculationV2Response": {
+            "type": "object",
+            "properties": {
+                "deductions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.DeductionLine"
+                    }
+                },
+                "distanceToNextBracket": {
+                    "type": "number",
+                    "example": 160000
+                },
+                "effectiveRate": {
+                    "type": "number",
+                    "example": 0.038
+                },
+                "grossTax": {
+                    "type": "number",
+                    "example": 19000
+                },
+                "marginalRate": {
+                    "type": "number",
+                    "example": 0.1
+                },
+                "netTax": {
+                    "type": "number",
+                    "example": -31000
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 0
+                },
+                "taxLevel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.TaxBracket"
+                    }
+                },
+                "taxRefund": {
+                    "type": "number",
+                    "example": 31000
+                },
+                "taxableIncome": {
+                    "type": "number",
+                    "example": 340000
+                },
+                "totalDeductions": {
+                    "type": "number",
+                    "example": 160000
+                },
+                "whtCredited": {
+                    "type": "number",
+                    "example": 50000
+                }
+            }
+        },
+        "schemas.TaxLevel": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string"
+                },
                 "tax": {
                     "type": "number"
                 }
             }
         },
+        "schemas.TaxYearConfigRequest": {
+            "type": "object",
+            "properties": {
+                "insurance": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceipt": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "personalDeduction": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "rmf": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "ssf": {
+                    "type": "number",
+                    "example": 200000
+                }
+            }
+        },
+        "schemas.TaxYearConfigResponse": {
+            "type": "object",
+            "properties": {
+                "configName": {
+                    "type": "string",
+                    "example": "TaxYear2025"
+                },
+                "donation": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "insurance": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceipt": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "personalDeduction": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "rmf": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "ssf": {
+                    "type": "number",
+                    "example": 200000
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2025
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "schemas.TaxYearConfigsResponse": {
+            "type": "object",
+            "properties": {
+                "taxYears": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.TaxYearConfigResponse"
+                    }
+                }
+            }
+        },
+        "schemas.UpdateInsuranceRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 100000
+                }
+            }
+        },
+        "schemas.UpdateInsuranceResponse": {
+            "type": "object",
+            "properties": {
+                "insurance": {
+                    "type": "number",
+                    "example": 100000
+                }
+            }
+        },
         "schemas.UpdateKReceiptRequest": {
             "type": "object",
             "properties": {
@@ -333,6 +2911,141 @@ const docTemplate = `{
                     "example": 60000
                 }
             }
+        },
+        "schemas.UpdateRMFRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 500000
+                }
+            }
+        },
+        "schemas.UpdateRMFResponse": {
+            "type": "object",
+            "properties": {
+                "rmf": {
+                    "type": "number",
+                    "example": 500000
+                }
+            }
+        },
+        "schemas.UpdateSSFRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 200000
+                }
+            }
+        },
+        "schemas.UpdateSSFResponse": {
+            "type": "object",
+            "properties": {
+                "ssf": {
+                    "type": "number",
+                    "example": 200000
+                }
+            }
+        },
+        "schemas.WithholdingMonth": {
+            "type": "object",
+            "properties": {
+                "bonus": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "bonusWithholding": {
+                    "type": "number",
+                    "example": 15000
+                },
+                "month": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "projectedAnnualIncome": {
+                    "type": "number",
+                    "example": 700000
+                },
+                "projectedAnnualTax": {
+                    "type": "number",
+                    "example": 56000
+                },
+                "salary": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "withheldToDate": {
+                    "type": "number",
+                    "example": 28666.68
+                },
+                "withholding": {
+                    "type": "number",
+                    "example": 18416.67
+                }
+            }
+        },
+        "schemas.WithholdingRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.Allowance"
+                    }
+                },
+                "monthlySalary": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "payments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.MonthlyPayment"
+                    }
+                }
+            }
+        },
+        "schemas.WithholdingResponse": {
+            "type": "object",
+            "properties": {
+                "annualIncome": {
+                    "type": "number",
+                    "example": 700000
+                },
+                "annualTax": {
+                    "type": "number",
+                    "example": 56000
+                },
+                "result": {
+                    "$ref": "#/definitions/schemas.TaxCalculationV2Response"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.WithholdingMonth"
+                    }
+                },
+                "totalWithheld": {
+                    "type": "number",
+                    "example": 56000
+                }
+            }
+        },
+        "schemas.YearAllowances": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.Allowance"
+                    }
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2025
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,20 @@
+package domains
+
+import "time"
+
+// IdempotencyRecord is the response stored for an Idempotency-Key. A record
+// is pending, with a StatusCode of 0, from the moment a request reserves the
+// key until its response is stored.
+type IdempotencyRecord struct {
+	Key          string    `gorm:"type:varchar(255);primaryKey"`
+	RequestHash  string    `gorm:"type:char(64);not null"`
+	StatusCode   int       `gorm:"not null"`
+	ContentType  string    `gorm:"type:varchar(255)"`
+	ResponseBody []byte    `gorm:"type:bytea"`
+	CreatedAt    time.Time `gorm:"not null"`
+}
+
+// Pending reports whether the request that reserved the key is still running.
+func (r *IdempotencyRecord) Pending() bool {
+	return r.StatusCode == 0
+}
@@ -1,8 +1,75 @@
 package domains
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrConfigVersionConflict is returned when the deduction configuration was
+// changed by someone else since the version the caller read.
+var ErrConfigVersionConflict = errors.New("deduction configuration was changed by another request")
+
+// ErrTaxYearConfigNotFound is returned when a tax year has no configuration to
+// update.
+var ErrTaxYearConfigNotFound = errors.New("the tax year has no deduction configuration")
+
+// ErrTaxYearConfigExists is returned when creating a configuration for a tax
+// year that already has one.
+var ErrTaxYearConfigExists = errors.New("the tax year already has a deduction configuration")
+
+// AnyConfigVersion passed as the expected version makes an update apply to
+// whatever version is current, as If-Match: * asks. Versions start at 1.
+const AnyConfigVersion int64 = 0
+
+// Allowance types a taxpayer can claim. Each is capped by a maximum in
+// TaxDeductionConfig.
+const (
+	AllowanceDonation  = "donation"
+	AllowanceKReceipt  = "k-receipt"
+	AllowanceInsurance = "insurance"
+	AllowanceRMF       = "rmf"
+	AllowanceSSF       = "ssf"
+)
+
+// AllowanceTypes lists every allowance type, in the order a deduction budget
+// is allocated to them.
+var AllowanceTypes = []string{AllowanceDonation, AllowanceKReceipt, AllowanceInsurance, AllowanceRMF, AllowanceSSF}
+
+// IsAllowanceType reports whether allowanceType is one of AllowanceTypes.
+func IsAllowanceType(allowanceType string) bool {
+	return slices.Contains(AllowanceTypes, allowanceType)
+}
+
+// TaxDeductionConfig is MainConfig, the live configuration admins update,
+// or, when TaxYear is set, the configuration for that tax year.
 type TaxDeductionConfig struct {
-	ConfigName           string  `gorm:"type:varchar(100);not null;unique"`
-	PersonalDeduction    float64 `gorm:"type:float;not null;check:personal_deduction >= 10000 and personal_deduction <= 100000"`
-	KReceiptDeductionMax float64 `gorm:"type:float;not null;check:k_receipt_deduction_max <= 100000"`
-	DonationDeductionMax float64 `gorm:"type:float;not null;default:100000"`
+	ConfigName            string  `gorm:"type:varchar(100);not null;unique"`
+	PersonalDeduction     float64 `gorm:"type:float;not null;check:personal_deduction >= 10000 and personal_deduction <= 100000"`
+	KReceiptDeductionMax  float64 `gorm:"type:float;not null;check:k_receipt_deduction_max <= 100000"`
+	DonationDeductionMax  float64 `gorm:"type:float;not null;default:100000"`
+	InsuranceDeductionMax float64 `gorm:"type:float;not null;default:100000;check:insurance_deduction_max <= 100000"`
+	RMFDeductionMax       float64 `gorm:"type:float;not null;default:500000;check:rmf_deduction_max <= 500000"`
+	SSFDeductionMax       float64 `gorm:"type:float;not null;default:200000;check:ssf_deduction_max <= 200000"`
+	Version               int64   `gorm:"not null;default:1"`
+	TaxYear               *int    `gorm:"unique"`
+}
+
+// DefaultTaxDeductionConfig is the MainConfig row a new database starts with.
+func DefaultTaxDeductionConfig() TaxDeductionConfig {
+	return TaxDeductionConfig{
+		ConfigName:            "MainConfig",
+		PersonalDeduction:     60000,
+		KReceiptDeductionMax:  50000,
+		DonationDeductionMax:  100000,
+		InsuranceDeductionMax: 100000,
+		RMFDeductionMax:       500000,
+		SSFDeductionMax:       200000,
+		Version:               1,
+	}
+}
+
+// TaxYearConfigName is the ConfigName of the configuration created for year.
+func TaxYearConfigName(year int) string {
+	return fmt.Sprintf("TaxYear%d", year)
 }
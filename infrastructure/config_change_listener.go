@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ConfigChangedChannel is the Postgres NOTIFY channel raised whenever a row of
+// tax_deduction_configs changes.
+const ConfigChangedChannel = "tax_deduction_config_changed"
+
+const configListenerRetryDelay = 5 * time.Second
+
+// ListenForConfigChanges calls onChange every time the deduction configuration
+// changes in the database, so replicas can drop their cached copy. It also
+// calls onChange after every (re)connect, as notifications sent while
+// disconnected are lost. It blocks until ctx is cancelled.
+func ListenForConfigChanges(ctx context.Context, databaseURL string, onChange func()) {
+	for ctx.Err() == nil {
+		if err := listenForConfigChanges(ctx, databaseURL, onChange); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "Config change listener stopped, retrying", "error", err, "retry_in", configListenerRetryDelay.String())
+			select {
+			case <-ctx.Done():
+			case <-time.After(configListenerRetryDelay):
+			}
+		}
+	}
+}
+
+func listenForConfigChanges(ctx context.Context, databaseURL string, onChange func()) error {
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+ConfigChangedChannel); err != nil {
+		return err
+	}
+	onChange()
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		onChange()
+	}
+}
@@ -1,51 +1,115 @@
 package infrastructure
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 
+	"github.com/glebarez/sqlite"
 	"github.com/thitiphum-bluesage/assessment-tax/config"
 	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/logging"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/migrations"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 )
 
-func InitializeDatabase() *gorm.DB {
-	cfg := config.GetConfig()
-	DATABASE_URI := cfg.DatabaseURL
+func InitializeDatabase(cfg *config.Config) *gorm.DB {
+	if cfg.DatabaseDriver == config.DatabaseDriverSQLite {
+		db, err := OpenSQLiteDatabase(cfg.DatabaseURL)
+		if err != nil {
+			logging.Fatal("Failed to open SQLite database", err)
+		}
+		slog.Info("Opened SQLite database")
+		return withQueryTracing(db)
+	}
+
+	db := OpenDatabase(cfg.DatabaseURL)
+
+	if err := ensureSchemaIsCurrent(db, cfg.AutoMigrate); err != nil {
+		logging.Fatal("Database schema is not ready", err)
+	}
+
+	slog.Info("Connected to database")
+	return withQueryTracing(db)
+}
+
+// withQueryTracing records a span for every query, as a child of the span in
+// the context passed to db.WithContext.
+func withQueryTracing(db *gorm.DB) *gorm.DB {
+	if err := db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics())); err != nil {
+		logging.Fatal("Failed to enable query tracing", err)
+	}
+	return db
+}
+
+func OpenDatabase(databaseURL string) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
+	if err != nil {
+		logging.Fatal("Failed to connect to database", err)
+	}
+	return db
+}
 
-	db, err := gorm.Open(postgres.Open(DATABASE_URI), &gorm.Config{})
+// OpenSQLiteDatabase opens (or creates) a SQLite database at path, which may be
+// ":memory:", and creates the schema with the default configuration. The SQL
+// migrations are written for Postgres, so the schema is derived from the
+// domain models instead.
+func OpenSQLiteDatabase(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return nil, err
 	}
 
-	if err := db.AutoMigrate(&domains.TaxDeductionConfig{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	// SQLite allows a single writer, and every connection to ":memory:" opens a
+	// separate database, so all queries share one connection.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(1)
 
-	if err := ensureDefaultConfigExists(db); err != nil {
-		log.Fatalf("Failed to initialize default configuration: %v", err)
+	if err := db.AutoMigrate(&domains.TaxDeductionConfig{}, &domains.IdempotencyRecord{}); err != nil {
+		return nil, err
 	}
 
-	log.Println("Successfully connected to database.")
-	return db
+	defaultConfig := domains.DefaultTaxDeductionConfig()
+	if err := db.Where("config_name = ?", defaultConfig.ConfigName).FirstOrCreate(&defaultConfig).Error; err != nil {
+		return nil, err
+	}
+	return db, nil
 }
 
-func ensureDefaultConfigExists(db *gorm.DB) error {
-	var count int64
-	if err := db.Model(&domains.TaxDeductionConfig{}).Count(&count).Error; err != nil {
+// ensureSchemaIsCurrent refuses to start on a database whose schema is older
+// than the embedded migrations, unless autoMigrate allows applying them.
+func ensureSchemaIsCurrent(db *gorm.DB, autoMigrate bool) error {
+	sqlDB, err := db.DB()
+	if err != nil {
 		return err
 	}
-	if count == 0 {
-		defaultConfig := domains.TaxDeductionConfig{
-			ConfigName:           "MainConfig",
-			PersonalDeduction:    60000,
-			KReceiptDeductionMax: 50000,
-			DonationDeductionMax: 100000,
-		}
-		if err := db.Create(&defaultConfig).Error; err != nil {
-			return err
-		}
-		log.Println("Main tax deduction configuration has been initialized.")
+	migrator, err := migrations.NewMigrator(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	current, err := migrator.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := migrator.LatestVersion()
+	switch {
+	case current == latest:
+		return nil
+	case current > latest:
+		slog.Warn("Database schema is newer than this build", "schema_version", current, "build_version", latest)
+		return nil
+	case autoMigrate:
+		slog.Info("Migrating database schema", "from_version", current, "to_version", latest)
+		return migrator.Up(ctx)
+	default:
+		return fmt.Errorf("database schema is at version %d but version %d is required; run `migrate up` or set DATABASE_AUTO_MIGRATE=true", current, latest)
 	}
-	return nil
 }
@@ -0,0 +1,46 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/migrations"
+	"gorm.io/gorm"
+)
+
+// PingDatabase returns a readiness check that fails when no connection to db
+// can be established.
+func PingDatabase(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// CheckMigrations returns a readiness check that fails while the database
+// schema is behind the migrations embedded in this build. The check only
+// reads, so probes never run DDL.
+func CheckMigrations(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		migrator, err := migrations.NewMigrator(sqlDB)
+		if err != nil {
+			return err
+		}
+
+		current, err := migrator.AppliedVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if latest := migrator.LatestVersion(); current < latest {
+			return fmt.Errorf("database schema is at version %d but version %d is required", current, latest)
+		}
+		return nil
+	}
+}
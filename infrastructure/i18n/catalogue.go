@@ -0,0 +1,136 @@
+package i18n
+
+import (
+	"golang.org/x/text/language"
+	"golang.org/x/text/message/catalog"
+)
+
+// messages maps every English message key to its Thai translation. Keys are
+// fmt format strings; the printer formats numeric arguments for the locale.
+var messages = map[string]string{
+	// Tax levels
+	"%d-%d":        "%d-%d",
+	"%d and above": "%d ขึ้นไป",
+
+	// Deductions
+	"Personal deduction of %v applies to every taxpayer": "ค่าลดหย่อนส่วนตัว %v ใช้กับผู้เสียภาษีทุกคน",
+	"Claimed amount is within the cap of %v":             "จำนวนที่ขอลดหย่อนไม่เกินเพดาน %v",
+	"Capped at the maximum of %v":                        "ลดหย่อนได้สูงสุด %v",
+
+	// Request validation
+	"Invalid input data":                     "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+	"validation errors: %s":                  "ข้อมูลไม่ผ่านการตรวจสอบ: %s",
+	"%s must be a %s":                        "%s ต้องเป็น%s",
+	"number":                                 "ตัวเลข",
+	"string":                                 "ข้อความ",
+	"boolean":                                "ค่าจริงหรือเท็จ",
+	"array":                                  "รายการ",
+	"object":                                 "อ็อบเจกต์",
+	"TotalIncome is required":                "ต้องระบุรายได้รวม",
+	"TotalIncome must be non-negative":       "รายได้รวมต้องไม่ติดลบ",
+	"TotalIncome must be a valid float":      "รายได้รวมต้องเป็นตัวเลขที่ถูกต้อง",
+	"WHT is required":                        "ต้องระบุภาษีหัก ณ ที่จ่าย",
+	"WHT must be non-negative":               "ภาษีหัก ณ ที่จ่ายต้องไม่ติดลบ",
+	"WHT must be a valid float":              "ภาษีหัก ณ ที่จ่ายต้องเป็นตัวเลขที่ถูกต้อง",
+	"WHT cannot be greater than TotalIncome": "ภาษีหัก ณ ที่จ่ายต้องไม่มากกว่ารายได้รวม",
+	"Donation must be non-negative":          "เงินบริจาคต้องไม่ติดลบ",
+	"Donation must be a valid float":         "เงินบริจาคต้องเป็นตัวเลขที่ถูกต้อง",
+	"KReceipt must be non-negative":          "ค่าลดหย่อน k-receipt ต้องไม่ติดลบ",
+	"KReceipt must be a valid float":         "ค่าลดหย่อน k-receipt ต้องเป็นตัวเลขที่ถูกต้อง",
+	"Invalid allowance type: %s. Allowed types are 'donation', 'k-receipt', 'insurance', 'rmf' and 'ssf'.": "ประเภทค่าลดหย่อนไม่ถูกต้อง: %s ใช้ได้เฉพาะ 'donation', 'k-receipt', 'insurance', 'rmf' และ 'ssf'",
+	"Amount for %s must be a valid float":                                     "จำนวนเงินของ %s ต้องเป็นตัวเลขที่ถูกต้อง",
+	"Amount for %s must be non-negative":                                      "จำนวนเงินของ %s ต้องไม่ติดลบ",
+	"Only one %s allowance can be included":                                   "ระบุค่าลดหย่อน %s ได้เพียงรายการเดียว",
+	"TargetNetIncome is required":                                             "ต้องระบุรายได้สุทธิที่ต้องการ",
+	"TargetNetIncome must be non-negative":                                    "รายได้สุทธิที่ต้องการต้องไม่ติดลบ",
+	"WHTRate is required for the rate policy":                                 "ต้องระบุอัตราภาษีหัก ณ ที่จ่ายเมื่อเลือกนโยบาย rate",
+	"WHTRate must be at least 0 and below 1":                                  "อัตราภาษีหัก ณ ที่จ่ายต้องไม่ต่ำกว่า 0 และน้อยกว่า 1",
+	"Invalid WHT policy: %s. Allowed policies are 'full', 'none' and 'rate'.": "นโยบายภาษีหัก ณ ที่จ่ายไม่ถูกต้อง: %s ใช้ได้เฉพาะ 'full', 'none' และ 'rate'",
+	"Budget is required":                                                      "ต้องระบุงบประมาณ",
+	"Budget must be non-negative":                                             "งบประมาณต้องไม่ติดลบ",
+	"%s can be listed only once":                                              "ระบุ %s ได้เพียงครั้งเดียว",
+	"MonthlySalary is required":                                               "ต้องระบุเงินเดือน",
+	"MonthlySalary must be non-negative":                                      "เงินเดือนต้องไม่ติดลบ",
+	"Month must be between 1 and 12":                                          "เดือนต้องอยู่ระหว่าง 1 ถึง 12",
+	"Only one payment can be included for month %d":                           "ระบุการจ่ายเงินของเดือนที่ %d ได้เพียงรายการเดียว",
+	"Salary must be non-negative":                                             "เงินเดือนต้องไม่ติดลบ",
+	"Bonus must be non-negative":                                              "โบนัสต้องไม่ติดลบ",
+	"StartYear is required":                                                   "ต้องระบุปีเริ่มต้น",
+	"StartYear must be between %s and %s":                                     "ปีเริ่มต้นต้องอยู่ระหว่าง %s ถึง %s",
+	"StartingIncome is required":                                              "ต้องระบุรายได้เริ่มต้น",
+	"StartingIncome must be non-negative":                                     "รายได้เริ่มต้นต้องไม่ติดลบ",
+	"GrowthRate must be above -1":                                             "อัตราการเติบโตต้องมากกว่า -1",
+	"Year must be between %s and %s":                                          "ปีต้องอยู่ระหว่าง %s ถึง %s",
+	"Only one plan can be included for year %s":                               "ระบุแผนค่าลดหย่อนของปี %s ได้เพียงแผนเดียว",
+	"base is required":                                                        "ต้องระบุกรณีฐาน",
+	"at least one variant is required":                                        "ต้องระบุกรณีเปรียบเทียบอย่างน้อยหนึ่งกรณี",
+	"at most %d variants can be compared":                                     "เปรียบเทียบได้สูงสุด %d กรณี",
+	"variant name is required":                                                "ต้องระบุชื่อกรณีเปรียบเทียบ",
+	"variant name %s is used more than once":                                  "ชื่อกรณีเปรียบเทียบ %s ซ้ำกัน",
+
+	// CSV uploads
+	"Invalid CSV file: %s":                 "ไฟล์ CSV ไม่ถูกต้อง: %s",
+	"%s '%s'":                              "%s '%s'",
+	"row %d, column %s: invalid value %q":  "แถวที่ %d คอลัมน์ %s: ค่าไม่ถูกต้อง %q",
+	"Record %d: %s":                        "รายการที่ %d: %s",
+	"invalid number":                       "ตัวเลขไม่ถูกต้อง",
+	"unknown column":                       "ไม่รู้จักคอลัมน์",
+	"duplicate column":                     "คอลัมน์ซ้ำ",
+	"missing required column":              "ไม่มีคอลัมน์ที่จำเป็น",
+	"Failed to get the file":               "ไม่พบไฟล์ที่อัปโหลด",
+	"Failed to open the file":              "ไม่สามารถเปิดไฟล์ที่อัปโหลดได้",
+	"Failed to decode the CSV file":        "ไม่สามารถอ่านไฟล์ CSV ได้",
+	"Failed to read headers from CSV file": "ไม่สามารถอ่านหัวตารางของไฟล์ CSV ได้",
+	"Failed to read record from CSV file":  "ไม่สามารถอ่านรายการจากไฟล์ CSV ได้",
+
+	// Batch
+	"Request canceled before every line was processed": "คำขอถูกยกเลิกก่อนประมวลผลครบทุกบรรทัด",
+
+	// Admin
+	"amount is required":                                     "ต้องระบุจำนวนเงิน",
+	"amount must be between 10,000 and 100,000":              "จำนวนเงินต้องอยู่ระหว่าง 10,000 ถึง 100,000",
+	"amount for k-receipt is required":                       "ต้องระบุจำนวนเงินสำหรับ k-receipt",
+	"amount for k-receipt must be between 1 and 100,000":     "จำนวนเงินสำหรับ k-receipt ต้องอยู่ระหว่าง 1 ถึง 100,000",
+	"amount for insurance is required":                       "ต้องระบุจำนวนเงินสำหรับ insurance",
+	"amount for insurance must be between 1 and 100,000":     "จำนวนเงินสำหรับ insurance ต้องอยู่ระหว่าง 1 ถึง 100,000",
+	"amount for rmf is required":                             "ต้องระบุจำนวนเงินสำหรับ rmf",
+	"amount for rmf must be between 1 and 500,000":           "จำนวนเงินสำหรับ rmf ต้องอยู่ระหว่าง 1 ถึง 500,000",
+	"amount for ssf is required":                             "ต้องระบุจำนวนเงินสำหรับ ssf",
+	"amount for ssf must be between 1 and 200,000":           "จำนวนเงินสำหรับ ssf ต้องอยู่ระหว่าง 1 ถึง 200,000",
+	"year must be a whole number":                            "ปีต้องเป็นจำนวนเต็ม",
+	"year must be between %s and %s":                         "ปีต้องอยู่ระหว่าง %s ถึง %s",
+	"personalDeduction must be between 10,000 and 100,000":   "ค่าลดหย่อนส่วนตัวต้องอยู่ระหว่าง 10,000 ถึง 100,000",
+	"kReceipt must be between 1 and 100,000":                 "วงเงิน k-receipt ต้องอยู่ระหว่าง 1 ถึง 100,000",
+	"insurance must be between 1 and 100,000":                "วงเงิน insurance ต้องอยู่ระหว่าง 1 ถึง 100,000",
+	"rmf must be between 1 and 500,000":                      "วงเงิน rmf ต้องอยู่ระหว่าง 1 ถึง 500,000",
+	"ssf must be between 1 and 200,000":                      "วงเงิน ssf ต้องอยู่ระหว่าง 1 ถึง 200,000",
+	"the tax year has no deduction configuration":            "ปีภาษีนี้ยังไม่มีการตั้งค่าค่าลดหย่อน",
+	"the tax year already has a deduction configuration":     "ปีภาษีนี้มีการตั้งค่าค่าลดหย่อนอยู่แล้ว",
+	"If-Match header is required":                            "ต้องส่ง header If-Match",
+	"deduction configuration was changed by another request": "การตั้งค่าค่าลดหย่อนถูกแก้ไขโดยคำขออื่นแล้ว กรุณาโหลดใหม่แล้วลองอีกครั้ง",
+	"Unauthorized: Incorrect credentials":                    "ไม่ได้รับอนุญาต: ชื่อผู้ใช้หรือรหัสผ่านไม่ถูกต้อง",
+
+	// Generic HTTP errors
+	"Not Found":             "ไม่พบสิ่งที่ร้องขอ",
+	"Method Not Allowed":    "ไม่รองรับเมธอดนี้",
+	"Internal Server Error": "เกิดข้อผิดพลาดภายในระบบ",
+}
+
+var catalogue, keys = buildCatalogue()
+
+// buildCatalogue registers the English catalogue, where every key translates
+// to itself, and the Thai catalogue.
+func buildCatalogue() (*catalog.Builder, map[string]bool) {
+	builder := catalog.NewBuilder(catalog.Fallback(language.English))
+	keys := make(map[string]bool, len(messages))
+	for key, thai := range messages {
+		keys[key] = true
+		if err := builder.SetString(language.English, key, key); err != nil {
+			panic(err)
+		}
+		if err := builder.SetString(language.Thai, key, thai); err != nil {
+			panic(err)
+		}
+	}
+	return builder, keys
+}
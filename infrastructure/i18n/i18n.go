@@ -0,0 +1,72 @@
+// Package i18n picks the language of API messages from Accept-Language and
+// renders them from the English and Thai message catalogues. Message keys are
+// the English text, so a message missing from a catalogue falls back to
+// English.
+package i18n
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Supported lists the languages with a catalogue, preferred first.
+var Supported = []language.Tag{language.English, language.Thai}
+
+var matcher = language.NewMatcher(Supported)
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx that carries the client's language.
+func WithLocale(ctx context.Context, locale language.Tag) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the language stored in ctx, or language.Und if the client
+// did not ask for one.
+func Locale(ctx context.Context) language.Tag {
+	locale, ok := ctx.Value(localeKey{}).(language.Tag)
+	if !ok {
+		return language.Und
+	}
+	return locale
+}
+
+// Match returns the supported language that best fits an Accept-Language
+// header, or language.Und if the header is empty or cannot be parsed.
+func Match(acceptLanguage string) language.Tag {
+	if acceptLanguage == "" {
+		return language.Und
+	}
+	preferred, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(preferred) == 0 {
+		return language.Und
+	}
+	_, index, _ := matcher.Match(preferred...)
+	return Supported[index]
+}
+
+// NewPrinter returns a printer that translates keys and formats numbers for
+// locale. language.Und prints English.
+func NewPrinter(locale language.Tag) *message.Printer {
+	if locale == language.Und {
+		locale = language.English
+	}
+	return message.NewPrinter(locale, message.Catalog(catalogue))
+}
+
+// Printer returns a printer for the language stored in ctx.
+func Printer(ctx context.Context) *message.Printer {
+	return NewPrinter(Locale(ctx))
+}
+
+// Translate returns the translation of a message without arguments in the
+// language stored in ctx. Messages that are not in the catalogues, such as
+// error text from a dependency, are returned unchanged.
+func Translate(ctx context.Context, msg string) string {
+	if !keys[msg] {
+		return msg
+	}
+	return Printer(ctx).Sprintf(msg)
+}
@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		expected       language.Tag
+	}{
+		{"", language.Und},
+		{"not a language;;", language.Und},
+		{"th", language.Thai},
+		{"th-TH,th;q=0.9,en;q=0.8", language.Thai},
+		{"en-US,en;q=0.9", language.English},
+		{"fr-FR,th;q=0.5", language.Thai},
+		{"fr-FR", language.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Match(tt.acceptLanguage))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	thai := WithLocale(context.Background(), language.Thai)
+	english := WithLocale(context.Background(), language.English)
+
+	assert.Equal(t, "รายได้รวมต้องไม่ติดลบ", Translate(thai, "TotalIncome must be non-negative"))
+	assert.Equal(t, "TotalIncome must be non-negative", Translate(english, "TotalIncome must be non-negative"))
+	assert.Equal(t, "TotalIncome must be non-negative", Translate(context.Background(), "TotalIncome must be non-negative"))
+	assert.Equal(t, "ไม่สามารถเปิดไฟล์ที่อัปโหลดได้", Translate(thai, "Failed to open the file"))
+	assert.Equal(t, "connection refused: 100%", Translate(thai, "connection refused: 100%"), "unknown messages are left alone")
+}
+
+func TestPrinter_FormatsNumbers(t *testing.T) {
+	assert.Equal(t, "2,000,001 and above", NewPrinter(language.English).Sprintf("%d and above", 2000001))
+	assert.Equal(t, "2,000,001 ขึ้นไป", NewPrinter(language.Thai).Sprintf("%d and above", 2000001))
+	assert.Equal(t, "จำนวนเงินของ donation ต้องไม่ติดลบ", NewPrinter(language.Thai).Sprintf("Amount for %s must be non-negative", "donation"))
+}
+
+func TestCataloguesHaveMatchingVerbs(t *testing.T) {
+	for key, thai := range messages {
+		assert.Equal(t, verbs(key), verbs(thai), key)
+	}
+}
+
+// verbs lists the formatting verbs of a format string in order.
+func verbs(format string) []byte {
+	var found []byte
+	for i := 0; i < len(format)-1; i++ {
+		if format[i] == '%' {
+			found = append(found, format[i+1])
+			i++
+		}
+	}
+	return found
+}
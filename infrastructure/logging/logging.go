@@ -0,0 +1,102 @@
+// Package logging configures the structured JSON logger and carries the
+// request ID through contexts so every log line of a request can be found.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values reveal a taxpayer's income or
+// deductions. They are compared case-insensitively.
+var sensitiveKeys = map[string]bool{
+	"income":      true,
+	"totalincome": true,
+	"wht":         true,
+	"allowances":  true,
+	"donation":    true,
+	"kreceipt":    true,
+	"k-receipt":   true,
+	"tax":         true,
+	"taxrefund":   true,
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx that carries requestID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// New returns a JSON logger writing to w at level. Records logged with a
+// context, e.g. slog.InfoContext, get the request ID and trace ID from it, and
+// sensitive attributes are redacted.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactSensitive,
+	})
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel turns "debug", "info", "warn" or "error" into a level, falling
+// back to info.
+func ParseLevel(level string) slog.Level {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return parsed
+}
+
+func redactSensitive(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Fatal logs msg with err at error level and exits.
+func Fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
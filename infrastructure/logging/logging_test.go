@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v: %s", err, buf.String())
+	}
+	return line
+}
+
+func TestNew_AddsRequestID(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(buf, slog.LevelInfo)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	logger.InfoContext(ctx, "Calculated taxes from CSV", "rows", 3)
+
+	line := decodeLogLine(t, buf)
+	assert.Equal(t, "Calculated taxes from CSV", line["msg"])
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.Equal(t, 3.0, line["rows"])
+	assert.NotContains(t, line, "trace_id")
+}
+
+func TestNew_RedactsIncomeData(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(buf, slog.LevelInfo)
+
+	logger.With("totalIncome", 500000.0).Info("Calculated tax", "wht", 25000.0, "TaxRefund", 1000.0, "amount", 60000.0, "row", 2)
+
+	line := decodeLogLine(t, buf)
+	assert.Equal(t, "[REDACTED]", line["totalIncome"])
+	assert.Equal(t, "[REDACTED]", line["wht"])
+	assert.Equal(t, "[REDACTED]", line["TaxRefund"])
+	assert.Equal(t, 60000.0, line["amount"])
+	assert.Equal(t, 2.0, line["row"])
+}
+
+func TestNew_RespectsLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(buf, ParseLevel("warn"))
+
+	logger.Info("ignored")
+	assert.Empty(t, buf.String())
+	logger.Warn("kept")
+	assert.Contains(t, buf.String(), "kept")
+}
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, ParseLevel("debug"))
+	assert.Equal(t, slog.LevelError, ParseLevel("ERROR"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel("verbose"))
+}
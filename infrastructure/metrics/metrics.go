@@ -0,0 +1,110 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
+const namespace = "ktax"
+
+const (
+	OutcomeTax    = "tax"
+	OutcomeRefund = "refund"
+	OutcomeNone   = "none"
+
+	ResultSuccess  = "success"
+	ResultConflict = "conflict"
+	ResultError    = "error"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	TaxCalculations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tax_calculations_total",
+		Help:      "Tax calculations by outcome: tax to pay, refund, or neither.",
+	}, []string{"outcome"})
+
+	CSVRowsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "csv_rows_processed_total",
+		Help:      "CSV rows whose tax was calculated.",
+	})
+
+	CSVRowsRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "csv_rows_rejected_total",
+		Help:      "CSV rows rejected because they could not be parsed or failed validation.",
+	})
+
+	BatchLines = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batch_lines_total",
+		Help:      "Lines of NDJSON batch requests by result.",
+	}, []string{"result"})
+
+	ConfigReads = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reads_total",
+		Help:      "Reads of the deduction configuration.",
+	})
+
+	ConfigCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_cache_hits_total",
+		Help:      "Reads of the deduction configuration served from the in-memory cache.",
+	})
+
+	AdminUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "admin_updates_total",
+		Help:      "Admin changes to the deduction configuration by setting and result.",
+	}, []string{"setting", "result"})
+)
+
+// TaxOutcome classifies a calculation result for TaxCalculations.
+func TaxOutcome(tax, taxRefund float64) string {
+	switch {
+	case tax > 0:
+		return OutcomeTax
+	case taxRefund > 0:
+		return OutcomeRefund
+	default:
+		return OutcomeNone
+	}
+}
+
+// UpdateResult classifies the error of an admin update for AdminUpdates.
+func UpdateResult(err error) string {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case errors.Is(err, domains.ErrConfigVersionConflict):
+		return ResultConflict
+	default:
+		return ResultError
+	}
+}
+
+// RegisterDBStats exports the connection pool statistics of db.
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
@@ -0,0 +1,210 @@
+package migrations
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"sql/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"sql/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"sql/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+
+	migrations, err := loadMigrations(fsys, "sql")
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "first", Up: "CREATE TABLE a ();", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "second", Up: "CREATE TABLE b ();", Down: "DROP TABLE b;"},
+	}, migrations)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		expected string
+	}{
+		{"Missing down file", fstest.MapFS{
+			"sql/0001_first.up.sql": {Data: []byte("CREATE TABLE a ();")},
+		}, "migration 1_first needs both an up and a down file"},
+		{"Invalid file name", fstest.MapFS{
+			"sql/first.sql": {Data: []byte("CREATE TABLE a ();")},
+		}, `invalid migration file name "first.sql"`},
+		{"Conflicting names", fstest.MapFS{
+			"sql/0001_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+			"sql/0001_other.down.sql": {Data: []byte("DROP TABLE a;")},
+		}, `migration 1 has conflicting names "first" and "other"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.fsys, "sql")
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(embeddedMigrations, "sql")
+	assert.NoError(t, err)
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "migration versions should be contiguous")
+	}
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "first", Up: "CREATE TABLE a ();", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "second", Up: "CREATE TABLE b ();", Down: "DROP TABLE b;"},
+		{Version: 3, Name: "third", Up: "CREATE TABLE c ();", Down: "DROP TABLE c;"},
+	}}, mock
+}
+
+func expectLocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectCurrentVersion(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, script string, version int, name string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, name, applied_at\) VALUES \(\$1, \$2, NOW\(\)\)`).
+		WithArgs(version, name).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+func expectReverted(mock sqlmock.Sqlmock, script string, version int) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+		WithArgs(version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestMigratorUp(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLocked(mock)
+	expectCurrentVersion(mock, 1)
+	expectApplied(mock, "CREATE TABLE b ();", 2, "second")
+	expectApplied(mock, "CREATE TABLE c ();", 3, "third")
+	expectUnlocked(mock)
+
+	assert.NoError(t, migrator.Up(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorDown(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLocked(mock)
+	expectCurrentVersion(mock, 3)
+	expectReverted(mock, "DROP TABLE c;", 3)
+	expectUnlocked(mock)
+
+	assert.NoError(t, migrator.Down(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorTo(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLocked(mock)
+	expectCurrentVersion(mock, 3)
+	expectReverted(mock, "DROP TABLE c;", 3)
+	expectReverted(mock, "DROP TABLE b;", 2)
+	expectUnlocked(mock)
+
+	assert.NoError(t, migrator.To(context.Background(), 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	err := migrator.To(context.Background(), 7)
+	assert.EqualError(t, err, "unknown migration version 7")
+}
+
+func TestMigratorUp_FailedMigrationRollsBack(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLocked(mock)
+	expectCurrentVersion(mock, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE c ();")).WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	expectUnlocked(mock)
+
+	err := migrator.Up(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, err.Error(), "migration 3_third failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorUp_ReadsVersionUnderLock(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	// Another replica applied every migration while this one waited for the
+	// lock, so there is nothing left to do.
+	expectLocked(mock)
+	expectCurrentVersion(mock, 3)
+	expectUnlocked(mock)
+
+	assert.NoError(t, migrator.Up(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorUp_LockFailed(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockID).WillReturnError(assert.AnError)
+
+	err := migrator.Up(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorAppliedVersion(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+	// Without the table nothing has been migrated, and the table is not created.
+	version, err := migrator.AppliedVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	version, err = migrator.AppliedVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS tax_deduction_configs;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS tax_deduction_configs (
+    config_name varchar(100) NOT NULL UNIQUE,
+    personal_deduction float NOT NULL CHECK (personal_deduction >= 10000 AND personal_deduction <= 100000),
+    k_receipt_deduction_max float NOT NULL CHECK (k_receipt_deduction_max <= 100000),
+    donation_deduction_max float NOT NULL DEFAULT 100000
+);
+
+INSERT INTO tax_deduction_configs (config_name, personal_deduction, k_receipt_deduction_max, donation_deduction_max)
+VALUES ('MainConfig', 60000, 50000, 100000)
+ON CONFLICT (config_name) DO NOTHING;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    key varchar(255) PRIMARY KEY,
+    request_hash char(64) NOT NULL,
+    status_code bigint NOT NULL,
+    content_type varchar(255),
+    response_body bytea,
+    created_at timestamptz NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS tax_deduction_config_changed ON tax_deduction_configs;
+DROP FUNCTION IF EXISTS notify_tax_deduction_config_changed();
@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION notify_tax_deduction_config_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('tax_deduction_config_changed', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tax_deduction_config_changed ON tax_deduction_configs;
+
+CREATE TRIGGER tax_deduction_config_changed
+AFTER INSERT OR UPDATE OR DELETE ON tax_deduction_configs
+FOR EACH STATEMENT EXECUTE FUNCTION notify_tax_deduction_config_changed();
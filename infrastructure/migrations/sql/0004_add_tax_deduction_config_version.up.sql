@@ -0,0 +1 @@
+ALTER TABLE tax_deduction_configs ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
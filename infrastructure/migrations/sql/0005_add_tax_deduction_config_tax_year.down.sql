@@ -0,0 +1 @@
+ALTER TABLE tax_deduction_configs DROP COLUMN IF EXISTS tax_year;
@@ -0,0 +1,4 @@
+ALTER TABLE tax_deduction_configs
+    DROP COLUMN IF EXISTS insurance_deduction_max,
+    DROP COLUMN IF EXISTS rmf_deduction_max,
+    DROP COLUMN IF EXISTS ssf_deduction_max;
@@ -0,0 +1,4 @@
+ALTER TABLE tax_deduction_configs
+    ADD COLUMN IF NOT EXISTS insurance_deduction_max float NOT NULL DEFAULT 100000 CHECK (insurance_deduction_max <= 100000),
+    ADD COLUMN IF NOT EXISTS rmf_deduction_max float NOT NULL DEFAULT 500000 CHECK (rmf_deduction_max <= 500000),
+    ADD COLUMN IF NOT EXISTS ssf_deduction_max float NOT NULL DEFAULT 200000 CHECK (ssf_deduction_max <= 200000);
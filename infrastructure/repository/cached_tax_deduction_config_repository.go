@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CachedTaxDeductionConfigRepository keeps the deduction configuration in
+// memory for up to ttl. Updates made through it invalidate the cache right
+// away; updates made by other replicas are picked up when Invalidate is
+// called, e.g. from a Postgres LISTEN/NOTIFY listener, or when ttl expires.
+type CachedTaxDeductionConfigRepository struct {
+	next TaxDeductionConfigRepositoryInterface
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu         sync.RWMutex
+	config     *domains.TaxDeductionConfig
+	expiresAt  time.Time
+	generation uint64
+}
+
+func NewCachedTaxDeductionConfigRepository(next TaxDeductionConfigRepositoryInterface, ttl time.Duration) *CachedTaxDeductionConfigRepository {
+	return &CachedTaxDeductionConfigRepository{
+		next: next,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+func (r *CachedTaxDeductionConfigRepository) GetConfig(ctx context.Context) (_ *domains.TaxDeductionConfig, err error) {
+	ctx, span := tracing.Start(ctx, "CachedTaxDeductionConfigRepository.GetConfig")
+	defer func() { tracing.End(span, err) }()
+
+	metrics.ConfigReads.Inc()
+
+	r.mu.RLock()
+	config, expiresAt, generation := r.config, r.expiresAt, r.generation
+	r.mu.RUnlock()
+
+	if config != nil && r.now().Before(expiresAt) {
+		metrics.ConfigCacheHits.Inc()
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		cached := *config
+		return &cached, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	config, err = r.next.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	// Only cache the result if nothing invalidated the cache while it was being
+	// loaded, otherwise a stale config could be stored.
+	if r.generation == generation {
+		cached := *config
+		r.config = &cached
+		r.expiresAt = r.now().Add(r.ttl)
+	}
+	r.mu.Unlock()
+
+	return config, nil
+}
+
+// GetTaxYearConfigs is not cached; only projections read tax year
+// configurations.
+func (r *CachedTaxDeductionConfigRepository) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	return r.next.GetTaxYearConfigs(ctx)
+}
+
+// Updates invalidate the cache whether or not they succeed; after a version
+// conflict the cached copy is known to be stale.
+func (r *CachedTaxDeductionConfigRepository) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	defer r.Invalidate()
+	return r.next.UpdatePersonalDeduction(ctx, amount, version)
+}
+
+func (r *CachedTaxDeductionConfigRepository) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	defer r.Invalidate()
+	return r.next.UpdateKReceiptDeductionMax(ctx, amount, version)
+}
+
+func (r *CachedTaxDeductionConfigRepository) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	defer r.Invalidate()
+	return r.next.UpdateInsuranceDeductionMax(ctx, amount, version)
+}
+
+func (r *CachedTaxDeductionConfigRepository) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	defer r.Invalidate()
+	return r.next.UpdateRMFDeductionMax(ctx, amount, version)
+}
+
+func (r *CachedTaxDeductionConfigRepository) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	defer r.Invalidate()
+	return r.next.UpdateSSFDeductionMax(ctx, amount, version)
+}
+
+// Tax year configurations are not cached, so writing them leaves the cached
+// MainConfig alone.
+func (r *CachedTaxDeductionConfigRepository) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	return r.next.CreateTaxYearConfig(ctx, config)
+}
+
+func (r *CachedTaxDeductionConfigRepository) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	return r.next.UpdateTaxYearConfig(ctx, config, version)
+}
+
+// Invalidate drops the cached configuration so the next read goes to the
+// database.
+func (r *CachedTaxDeductionConfigRepository) Invalidate() {
+	r.mu.Lock()
+	r.config = nil
+	r.generation++
+	r.mu.Unlock()
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
+)
+
+type MockTaxDeductionConfigRepository struct {
+	mock.Mock
+}
+
+func (m *MockTaxDeductionConfigRepository) GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error) {
+	args := m.Called()
+	if config, ok := args.Get(0).(*domains.TaxDeductionConfig); ok {
+		return config, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	args := m.Called()
+	if configs, ok := args.Get(0).([]domains.TaxDeductionConfig); ok {
+		return configs, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaxDeductionConfigRepository) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	args := m.Called(config)
+	return args.Error(0)
+}
+
+func (m *MockTaxDeductionConfigRepository) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	args := m.Called(config, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newTestCachedRepository(inner TaxDeductionConfigRepositoryInterface) (*CachedTaxDeductionConfigRepository, *time.Time) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	cachedRepo := NewCachedTaxDeductionConfigRepository(inner, time.Minute)
+	cachedRepo.now = func() time.Time { return now }
+	return cachedRepo, &now
+}
+
+func TestCachedGetConfig_ServesFromCacheUntilExpired(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{ConfigName: "MainConfig", PersonalDeduction: 60000}, nil).Twice()
+
+	cachedRepo, now := newTestCachedRepository(inner)
+
+	for i := 0; i < 3; i++ {
+		config, err := cachedRepo.GetConfig(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 60000.0, config.PersonalDeduction)
+	}
+	inner.AssertNumberOfCalls(t, "GetConfig", 1)
+
+	*now = now.Add(2 * time.Minute)
+	_, err := cachedRepo.GetConfig(context.Background())
+	assert.NoError(t, err)
+	inner.AssertNumberOfCalls(t, "GetConfig", 2)
+}
+
+func TestCachedGetConfig_ReturnsCopies(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{ConfigName: "MainConfig", PersonalDeduction: 60000}, nil).Once()
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+
+	config, err := cachedRepo.GetConfig(context.Background())
+	assert.NoError(t, err)
+	config.PersonalDeduction = 1
+
+	config, err = cachedRepo.GetConfig(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 60000.0, config.PersonalDeduction)
+}
+
+func TestCachedGetConfig_DoesNotCacheErrors(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(nil, errors.New("connection refused")).Once()
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{ConfigName: "MainConfig"}, nil).Once()
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+
+	_, err := cachedRepo.GetConfig(context.Background())
+	assert.Error(t, err)
+	config, err := cachedRepo.GetConfig(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "MainConfig", config.ConfigName)
+}
+
+func TestCachedUpdates_InvalidateCache(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil).Once()
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 70000}, nil).Once()
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 70000, KReceiptDeductionMax: 45000}, nil).Once()
+	inner.On("UpdatePersonalDeduction", 70000.0, int64(1)).Return(int64(2), nil)
+	inner.On("UpdateKReceiptDeductionMax", 45000.0, int64(2)).Return(int64(3), nil)
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+
+	config, _ := cachedRepo.GetConfig(context.Background())
+	assert.Equal(t, 60000.0, config.PersonalDeduction)
+
+	_, err := cachedRepo.UpdatePersonalDeduction(context.Background(), 70000, 1)
+	assert.NoError(t, err)
+	config, _ = cachedRepo.GetConfig(context.Background())
+	assert.Equal(t, 70000.0, config.PersonalDeduction)
+
+	_, err = cachedRepo.UpdateKReceiptDeductionMax(context.Background(), 45000, 2)
+	assert.NoError(t, err)
+	config, _ = cachedRepo.GetConfig(context.Background())
+	assert.Equal(t, 45000.0, config.KReceiptDeductionMax)
+
+	inner.AssertExpectations(t)
+}
+
+func TestCachedAllowanceCapUpdates_InvalidateCache(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{Version: 1}, nil)
+	inner.On("UpdateInsuranceDeductionMax", 90000.0, int64(1)).Return(int64(2), nil)
+	inner.On("UpdateRMFDeductionMax", 400000.0, int64(2)).Return(int64(3), nil)
+	inner.On("UpdateSSFDeductionMax", 150000.0, int64(3)).Return(int64(4), nil)
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+
+	_, _ = cachedRepo.GetConfig(context.Background())
+	_, err := cachedRepo.UpdateInsuranceDeductionMax(context.Background(), 90000, 1)
+	assert.NoError(t, err)
+	_, _ = cachedRepo.GetConfig(context.Background())
+	_, err = cachedRepo.UpdateRMFDeductionMax(context.Background(), 400000, 2)
+	assert.NoError(t, err)
+	_, _ = cachedRepo.GetConfig(context.Background())
+	_, err = cachedRepo.UpdateSSFDeductionMax(context.Background(), 150000, 3)
+	assert.NoError(t, err)
+	_, _ = cachedRepo.GetConfig(context.Background())
+
+	inner.AssertNumberOfCalls(t, "GetConfig", 4)
+	inner.AssertExpectations(t)
+}
+
+func TestCachedUpdates_VersionConflictInvalidatesCache(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000, Version: 1}, nil)
+	inner.On("UpdatePersonalDeduction", 70000.0, int64(1)).Return(int64(0), domains.ErrConfigVersionConflict)
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+
+	_, _ = cachedRepo.GetConfig(context.Background())
+	_, err := cachedRepo.UpdatePersonalDeduction(context.Background(), 70000, 1)
+	assert.ErrorIs(t, err, domains.ErrConfigVersionConflict)
+	_, _ = cachedRepo.GetConfig(context.Background())
+
+	inner.AssertNumberOfCalls(t, "GetConfig", 2)
+}
+
+func TestCachedInvalidate(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil)
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+
+	_, _ = cachedRepo.GetConfig(context.Background())
+	cachedRepo.Invalidate()
+	_, _ = cachedRepo.GetConfig(context.Background())
+
+	inner.AssertNumberOfCalls(t, "GetConfig", 2)
+}
+
+func TestCachedGetConfig_RecordsCacheHits(t *testing.T) {
+	inner := new(MockTaxDeductionConfigRepository)
+	inner.On("GetConfig").Return(&domains.TaxDeductionConfig{PersonalDeduction: 60000}, nil)
+
+	cachedRepo, _ := newTestCachedRepository(inner)
+	readsBefore := testutil.ToFloat64(metrics.ConfigReads)
+	hitsBefore := testutil.ToFloat64(metrics.ConfigCacheHits)
+
+	for i := 0; i < 3; i++ {
+		_, _ = cachedRepo.GetConfig(context.Background())
+	}
+
+	assert.Equal(t, readsBefore+3, testutil.ToFloat64(metrics.ConfigReads))
+	assert.Equal(t, hitsBefore+2, testutil.ToFloat64(metrics.ConfigCacheHits))
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
+// A request reserves its key with a pending record before it is handled, so
+// concurrent requests with the same key cannot both run. The record is then
+// completed with the response, or released if there is no response to keep.
+type IdempotencyRepositoryInterface interface {
+	// FindByKey returns nil without an error when no record exists for key.
+	FindByKey(ctx context.Context, key string) (*domains.IdempotencyRecord, error)
+	// Reserve inserts record unless the key is taken by a record created at
+	// or after expiredBefore, and reports whether the key was reserved.
+	Reserve(ctx context.Context, record *domains.IdempotencyRecord, expiredBefore time.Time) (bool, error)
+	// Complete stores the response of a pending record with the same key and
+	// request hash. Completed records are never changed.
+	Complete(ctx context.Context, record *domains.IdempotencyRecord) error
+	// Release deletes the pending record for key and requestHash.
+	Release(ctx context.Context, key, requestHash string) error
+	// DeleteExpired deletes the records created before expiredBefore and
+	// returns how many there were.
+	DeleteExpired(ctx context.Context, expiredBefore time.Time) (int64, error)
+}
+
+// PurgeExpiredIdempotencyRecords deletes records older than ttl every
+// interval until ctx is done.
+func PurgeExpiredIdempotencyRecords(ctx context.Context, repo IdempotencyRepositoryInterface, ttl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := repo.DeleteExpired(ctx, time.Now().Add(-ttl))
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to purge expired idempotency records", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.InfoContext(ctx, "Purged expired idempotency records", "deleted", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewIdempotencyRepository(db *gorm.DB, queryTimeout time.Duration) IdempotencyRepositoryInterface {
+	return &idempotencyRepository{db: db, queryTimeout: queryTimeout}
+}
+
+func (r *idempotencyRepository) FindByKey(ctx context.Context, key string) (_ *domains.IdempotencyRecord, err error) {
+	ctx, span := tracing.Start(ctx, "idempotencyRepository.FindByKey")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var record domains.IdempotencyRecord
+	err = r.db.WithContext(ctx).Where("key = ?", key).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to look up idempotency key", "idempotency_key", key, "error", err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *domains.IdempotencyRecord, expiredBefore time.Time) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "idempotencyRepository.Reserve")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// An expired record is taken over in the same statement, so two requests
+	// cannot both see it expired and both reserve the key.
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"request_hash", "status_code", "content_type", "response_body", "created_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Lt{Column: clause.Column{Table: "idempotency_records", Name: "created_at"}, Value: expiredBefore},
+		}},
+	}).Create(record)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to reserve idempotency key", "idempotency_key", record.Key, "error", result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, record *domains.IdempotencyRecord) (err error) {
+	ctx, span := tracing.Start(ctx, "idempotencyRepository.Complete")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Model(&domains.IdempotencyRecord{}).
+		Where("key = ? AND request_hash = ? AND status_code = 0", record.Key, record.RequestHash).
+		Updates(map[string]interface{}{
+			"status_code":   record.StatusCode,
+			"content_type":  record.ContentType,
+			"response_body": record.ResponseBody,
+		}).Error
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key, requestHash string) (err error) {
+	ctx, span := tracing.Start(ctx, "idempotencyRepository.Release")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).
+		Where("key = ? AND request_hash = ? AND status_code = 0", key, requestHash).
+		Delete(&domains.IdempotencyRecord{}).Error
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, expiredBefore time.Time) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "idempotencyRepository.DeleteExpired")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Where("created_at < ?", expiredBefore).Delete(&domains.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
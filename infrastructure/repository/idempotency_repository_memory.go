@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
+type inMemoryIdempotencyRepository struct {
+	mu      sync.RWMutex
+	records map[string]domains.IdempotencyRecord
+}
+
+// NewInMemoryIdempotencyRepository keeps idempotency records in memory. Nothing
+// survives a restart, so it is meant for local development and tests.
+func NewInMemoryIdempotencyRepository() IdempotencyRepositoryInterface {
+	return &inMemoryIdempotencyRepository{records: map[string]domains.IdempotencyRecord{}}
+}
+
+func (r *inMemoryIdempotencyRepository) FindByKey(ctx context.Context, key string) (*domains.IdempotencyRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[key]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (r *inMemoryIdempotencyRepository) Reserve(ctx context.Context, record *domains.IdempotencyRecord, expiredBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[record.Key]; ok && !existing.CreatedAt.Before(expiredBefore) {
+		return false, nil
+	}
+	r.records[record.Key] = *record
+	return true, nil
+}
+
+func (r *inMemoryIdempotencyRepository) Complete(ctx context.Context, record *domains.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.records[record.Key]
+	if !ok || !existing.Pending() || existing.RequestHash != record.RequestHash {
+		return nil
+	}
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.ResponseBody = record.ResponseBody
+	r.records[record.Key] = existing
+	return nil
+}
+
+func (r *inMemoryIdempotencyRepository) Release(ctx context.Context, key, requestHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[key]; ok && existing.Pending() && existing.RequestHash == requestHash {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *inMemoryIdempotencyRepository) DeleteExpired(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, record := range r.records {
+		if record.CreatedAt.Before(expiredBefore) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
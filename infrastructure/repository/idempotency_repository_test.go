@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
+func TestIdempotencyFindByKey(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	idempotencyRepo := NewIdempotencyRepository(gdb, time.Second)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"key", "request_hash", "status_code", "content_type", "response_body", "created_at"}).
+		AddRow("retry-1", "abc", 200, "application/json", []byte(`{"taxes":[]}`), createdAt)
+
+	mock.ExpectQuery(`SELECT \* FROM "idempotency_records" WHERE key = \$1 ORDER BY "idempotency_records"\."key" LIMIT \$2`).
+		WithArgs("retry-1", 1).
+		WillReturnRows(rows)
+
+	record, err := idempotencyRepo.FindByKey(context.Background(), "retry-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &domains.IdempotencyRecord{
+		Key:          "retry-1",
+		RequestHash:  "abc",
+		StatusCode:   200,
+		ContentType:  "application/json",
+		ResponseBody: []byte(`{"taxes":[]}`),
+		CreatedAt:    createdAt,
+	}, record)
+
+	mock.ExpectQuery(`SELECT \* FROM "idempotency_records" WHERE key = \$1`).
+		WithArgs("missing", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}))
+
+	record, err = idempotencyRepo.FindByKey(context.Background(), "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyReserve(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	idempotencyRepo := NewIdempotencyRepository(gdb, time.Second)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	expiredBefore := createdAt.Add(-24 * time.Hour)
+	record := &domains.IdempotencyRecord{Key: "retry-1", RequestHash: "abc", CreatedAt: createdAt}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "idempotency_records" \("key","request_hash","status_code","content_type","response_body","created_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\) ON CONFLICT \("key"\) DO UPDATE SET .* WHERE "idempotency_records"\."created_at" < \$7`).
+		WithArgs(record.Key, record.RequestHash, 0, "", []byte(nil), createdAt, expiredBefore).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	reserved, err := idempotencyRepo.Reserve(context.Background(), record, expiredBefore)
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyComplete(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	idempotencyRepo := NewIdempotencyRepository(gdb, time.Second)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "idempotency_records" SET "content_type"=\$1,"response_body"=\$2,"status_code"=\$3 WHERE key = \$4 AND request_hash = \$5 AND status_code = 0`).
+		WithArgs("application/json", []byte(`{"taxes":[]}`), 200, "retry-1", "abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := idempotencyRepo.Complete(context.Background(), &domains.IdempotencyRecord{
+		Key:          "retry-1",
+		RequestHash:  "abc",
+		StatusCode:   200,
+		ContentType:  "application/json",
+		ResponseBody: []byte(`{"taxes":[]}`),
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeExpiredIdempotencyRecords(t *testing.T) {
+	idempotencyRepo := NewInMemoryIdempotencyRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for key, age := range map[string]time.Duration{"expired": 2 * time.Hour, "live": time.Minute} {
+		reserved, err := idempotencyRepo.Reserve(ctx, &domains.IdempotencyRecord{Key: key, RequestHash: "abc", CreatedAt: time.Now().Add(-age)}, time.Time{})
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+	}
+
+	go PurgeExpiredIdempotencyRecords(ctx, idempotencyRepo, time.Hour, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		record, err := idempotencyRepo.FindByKey(ctx, "expired")
+		return err == nil && record == nil
+	}, time.Second, 10*time.Millisecond)
+	record, err := idempotencyRepo.FindByKey(ctx, "live")
+	assert.NoError(t, err)
+	assert.NotNil(t, record)
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure"
+)
+
+type repositoryBackend struct {
+	name            string
+	taxConfigRepo   TaxDeductionConfigRepositoryInterface
+	idempotencyRepo IdempotencyRepositoryInterface
+}
+
+// newRepositoryBackends returns the in-memory repositories and the gorm
+// repositories on top of an in-memory SQLite database, so the same behaviour
+// can be checked against both.
+func newRepositoryBackends(t *testing.T) []repositoryBackend {
+	db, err := infrastructure.OpenSQLiteDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return []repositoryBackend{
+		{"memory", NewInMemoryTaxDeductionConfigRepository(), NewInMemoryIdempotencyRepository()},
+		{"sqlite", NewTaxDeductionConfigRepository(db, time.Second), NewIdempotencyRepository(db, time.Second)},
+	}
+}
+
+func TestTaxDeductionConfigRepositoryBackends(t *testing.T) {
+	for _, backend := range newRepositoryBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			config, err := backend.taxConfigRepo.GetConfig(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, domains.DefaultTaxDeductionConfig(), *config)
+
+			taxYearConfigs, err := backend.taxConfigRepo.GetTaxYearConfigs(ctx)
+			assert.NoError(t, err)
+			assert.Empty(t, taxYearConfigs)
+
+			version, err := backend.taxConfigRepo.UpdatePersonalDeduction(ctx, 70000, config.Version)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), version)
+			version, err = backend.taxConfigRepo.UpdateKReceiptDeductionMax(ctx, 45000, version)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), version)
+
+			// An update based on a version that has since changed must not apply.
+			_, err = backend.taxConfigRepo.UpdatePersonalDeduction(ctx, 80000, 2)
+			assert.ErrorIs(t, err, domains.ErrConfigVersionConflict)
+
+			// If-Match: * applies to whatever version is current.
+			version, err = backend.taxConfigRepo.UpdateKReceiptDeductionMax(ctx, 45000, domains.AnyConfigVersion)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(4), version)
+
+			version, err = backend.taxConfigRepo.UpdateInsuranceDeductionMax(ctx, 90000, version)
+			assert.NoError(t, err)
+			version, err = backend.taxConfigRepo.UpdateRMFDeductionMax(ctx, 400000, version)
+			assert.NoError(t, err)
+			version, err = backend.taxConfigRepo.UpdateSSFDeductionMax(ctx, 150000, version)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(7), version)
+
+			config, err = backend.taxConfigRepo.GetConfig(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 70000.0, config.PersonalDeduction)
+			assert.Equal(t, 45000.0, config.KReceiptDeductionMax)
+			assert.Equal(t, 100000.0, config.DonationDeductionMax)
+			assert.Equal(t, 90000.0, config.InsuranceDeductionMax)
+			assert.Equal(t, 400000.0, config.RMFDeductionMax)
+			assert.Equal(t, 150000.0, config.SSFDeductionMax)
+			assert.Equal(t, int64(7), config.Version)
+		})
+	}
+}
+
+func TestTaxYearConfigRepositoryBackends(t *testing.T) {
+	for _, backend := range newRepositoryBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			taxYearConfig := func(year int, personalDeduction float64) *domains.TaxDeductionConfig {
+				config := domains.DefaultTaxDeductionConfig()
+				config.TaxYear = &year
+				config.PersonalDeduction = personalDeduction
+				return &config
+			}
+
+			assert.NoError(t, backend.taxConfigRepo.CreateTaxYearConfig(ctx, taxYearConfig(2026, 70000)))
+			created := taxYearConfig(2025, 65000)
+			assert.NoError(t, backend.taxConfigRepo.CreateTaxYearConfig(ctx, created))
+			assert.Equal(t, "TaxYear2025", created.ConfigName)
+			assert.Equal(t, int64(1), created.Version)
+
+			err := backend.taxConfigRepo.CreateTaxYearConfig(ctx, taxYearConfig(2025, 80000))
+			assert.ErrorIs(t, err, domains.ErrTaxYearConfigExists)
+
+			version, err := backend.taxConfigRepo.UpdateTaxYearConfig(ctx, taxYearConfig(2025, 75000), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), version)
+
+			// An update based on a version that has since changed must not apply.
+			_, err = backend.taxConfigRepo.UpdateTaxYearConfig(ctx, taxYearConfig(2025, 90000), 1)
+			assert.ErrorIs(t, err, domains.ErrConfigVersionConflict)
+			_, err = backend.taxConfigRepo.UpdateTaxYearConfig(ctx, taxYearConfig(2030, 90000), 1)
+			assert.ErrorIs(t, err, domains.ErrTaxYearConfigNotFound)
+			_, err = backend.taxConfigRepo.UpdateTaxYearConfig(ctx, taxYearConfig(2030, 90000), domains.AnyConfigVersion)
+			assert.ErrorIs(t, err, domains.ErrTaxYearConfigNotFound)
+
+			version, err = backend.taxConfigRepo.UpdateTaxYearConfig(ctx, taxYearConfig(2025, 85000), domains.AnyConfigVersion)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), version)
+
+			configs, err := backend.taxConfigRepo.GetTaxYearConfigs(ctx)
+			assert.NoError(t, err)
+			if assert.Len(t, configs, 2) {
+				assert.Equal(t, 2025, *configs[0].TaxYear)
+				assert.Equal(t, "TaxYear2025", configs[0].ConfigName)
+				assert.Equal(t, 85000.0, configs[0].PersonalDeduction)
+				assert.Equal(t, int64(3), configs[0].Version)
+				assert.Equal(t, 2026, *configs[1].TaxYear)
+				assert.Equal(t, int64(1), configs[1].Version)
+			}
+
+			// MainConfig is not touched by tax year configurations.
+			config, err := backend.taxConfigRepo.GetConfig(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, domains.DefaultTaxDeductionConfig(), *config)
+		})
+	}
+}
+
+func TestIdempotencyRepositoryBackends(t *testing.T) {
+	for _, backend := range newRepositoryBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			record, err := backend.idempotencyRepo.FindByKey(ctx, "key-1")
+			assert.NoError(t, err)
+			assert.Nil(t, record)
+
+			createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			expiredBefore := createdAt.Add(-time.Hour)
+			reserve := func(hash string, createdAt, expiredBefore time.Time) bool {
+				reserved, err := backend.idempotencyRepo.Reserve(ctx, &domains.IdempotencyRecord{Key: "key-1", RequestHash: hash, CreatedAt: createdAt}, expiredBefore)
+				assert.NoError(t, err)
+				return reserved
+			}
+
+			assert.True(t, reserve("hash-1", createdAt, expiredBefore))
+			assert.False(t, reserve("hash-1", createdAt, expiredBefore), "a live key cannot be reserved twice")
+
+			record, err = backend.idempotencyRepo.FindByKey(ctx, "key-1")
+			assert.NoError(t, err)
+			if assert.NotNil(t, record) {
+				assert.True(t, record.Pending())
+			}
+
+			// A released key can be reserved again.
+			assert.NoError(t, backend.idempotencyRepo.Release(ctx, "key-1", "hash-1"))
+			assert.True(t, reserve("hash-1", createdAt, expiredBefore))
+
+			assert.NoError(t, backend.idempotencyRepo.Complete(ctx, &domains.IdempotencyRecord{
+				Key: "key-1", RequestHash: "hash-1", StatusCode: 200, ContentType: "application/json", ResponseBody: []byte(`{"tax":0}`),
+			}))
+			// Completed records are not overwritten or released.
+			assert.NoError(t, backend.idempotencyRepo.Complete(ctx, &domains.IdempotencyRecord{
+				Key: "key-1", RequestHash: "hash-1", StatusCode: 201, ContentType: "application/json", ResponseBody: []byte(`{"tax":1}`),
+			}))
+			assert.NoError(t, backend.idempotencyRepo.Release(ctx, "key-1", "hash-1"))
+
+			record, err = backend.idempotencyRepo.FindByKey(ctx, "key-1")
+			assert.NoError(t, err)
+			if assert.NotNil(t, record) {
+				assert.Equal(t, "hash-1", record.RequestHash)
+				assert.Equal(t, 200, record.StatusCode)
+				assert.Equal(t, []byte(`{"tax":0}`), record.ResponseBody)
+				assert.True(t, createdAt.Equal(record.CreatedAt))
+			}
+
+			// Once expired, the key is taken over by the next request.
+			later := createdAt.Add(2 * time.Hour)
+			assert.True(t, reserve("hash-2", later, later.Add(-time.Hour)))
+			record, err = backend.idempotencyRepo.FindByKey(ctx, "key-1")
+			assert.NoError(t, err)
+			if assert.NotNil(t, record) {
+				assert.Equal(t, "hash-2", record.RequestHash)
+				assert.True(t, record.Pending())
+			}
+
+			deleted, err := backend.idempotencyRepo.DeleteExpired(ctx, later)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), deleted)
+			deleted, err = backend.idempotencyRepo.DeleteExpired(ctx, later.Add(time.Second))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), deleted)
+			record, err = backend.idempotencyRepo.FindByKey(ctx, "key-1")
+			assert.NoError(t, err)
+			assert.Nil(t, record)
+		})
+	}
+}
@@ -1,9 +1,32 @@
 package repository
 
-import "github.com/thitiphum-bluesage/assessment-tax/domains"
+import (
+	"context"
 
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
+// GetConfig returns MainConfig, the live configuration. GetTaxYearConfigs
+// returns the configurations pinned to a tax year, ordered by year.
+//
+// The update methods change MainConfig, or the configuration of a tax year for
+// UpdateTaxYearConfig, and only apply when the stored configuration is still at
+// version, or at any version for domains.AnyConfigVersion. They return the new
+// version, or domains.ErrConfigVersionConflict when the configuration has been
+// changed in the meantime.
+//
+// CreateTaxYearConfig stores config, named after its tax year, at version 1.
+// It returns domains.ErrTaxYearConfigExists when the year already has a
+// configuration, and UpdateTaxYearConfig returns
+// domains.ErrTaxYearConfigNotFound when it has none.
 type TaxDeductionConfigRepositoryInterface interface {
-	GetConfig() (*domains.TaxDeductionConfig, error)
-	UpdatePersonalDeduction(amount float64) error
-	UpdateKReceiptDeductionMax(amount float64) error
+	GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error)
+	GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error)
+	UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error)
+	CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error
+	UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error)
 }
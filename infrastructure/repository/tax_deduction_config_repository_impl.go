@@ -1,39 +1,177 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type taxDeductionConfigRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	queryTimeout time.Duration
 }
 
-func NewTaxDeductionConfigRepository(db *gorm.DB) TaxDeductionConfigRepositoryInterface {
-	return &taxDeductionConfigRepository{db: db}
+func NewTaxDeductionConfigRepository(db *gorm.DB, queryTimeout time.Duration) TaxDeductionConfigRepositoryInterface {
+	return &taxDeductionConfigRepository{db: db, queryTimeout: queryTimeout}
 }
 
-func (r *taxDeductionConfigRepository) GetConfig() (*domains.TaxDeductionConfig, error) {
+func (r *taxDeductionConfigRepository) GetConfig(ctx context.Context) (_ *domains.TaxDeductionConfig, err error) {
+	ctx, span := tracing.Start(ctx, "taxDeductionConfigRepository.GetConfig")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	var config domains.TaxDeductionConfig
-	err := r.db.Where("config_name = ?", "MainConfig").First(&config).Error
+	err = r.db.WithContext(ctx).Where("config_name = ?", "MainConfig").First(&config).Error
 	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load deduction configuration", "error", err)
 		return nil, err
 	}
 	return &config, nil
 }
 
-func (r *taxDeductionConfigRepository) UpdatePersonalDeduction(amount float64) error {
-	result := r.db.Model(&domains.TaxDeductionConfig{}).Where("config_name = ?", "MainConfig").Update("personal_deduction", amount)
+func (r *taxDeductionConfigRepository) GetTaxYearConfigs(ctx context.Context) (_ []domains.TaxDeductionConfig, err error) {
+	ctx, span := tracing.Start(ctx, "taxDeductionConfigRepository.GetTaxYearConfigs")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var configs []domains.TaxDeductionConfig
+	err = r.db.WithContext(ctx).Where("tax_year IS NOT NULL").Order("tax_year").Find(&configs).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load tax year deduction configurations", "error", err)
+		return nil, err
+	}
+	return configs, nil
+}
+
+func (r *taxDeductionConfigRepository) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateMainConfig(ctx, "personal_deduction", amount, version)
+}
+
+func (r *taxDeductionConfigRepository) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateMainConfig(ctx, "k_receipt_deduction_max", amount, version)
+}
+
+func (r *taxDeductionConfigRepository) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateMainConfig(ctx, "insurance_deduction_max", amount, version)
+}
+
+func (r *taxDeductionConfigRepository) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateMainConfig(ctx, "rmf_deduction_max", amount, version)
+}
+
+func (r *taxDeductionConfigRepository) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateMainConfig(ctx, "ssf_deduction_max", amount, version)
+}
+
+func (r *taxDeductionConfigRepository) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) (err error) {
+	ctx, span := tracing.Start(ctx, "taxDeductionConfigRepository.CreateTaxYearConfig", attribute.Int("config.tax_year", *config.TaxYear))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	config.ConfigName = domains.TaxYearConfigName(*config.TaxYear)
+	config.Version = 1
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(config)
 	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to create tax year deduction configuration", "taxYear", *config.TaxYear, "error", result.Error)
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return domains.ErrTaxYearConfigExists
+	}
 	return nil
 }
 
-func (r *taxDeductionConfigRepository) UpdateKReceiptDeductionMax(amount float64) error {
-	result := r.db.Model(&domains.TaxDeductionConfig{}).Where("config_name = ?", "MainConfig").Update("k_receipt_deduction_max", amount)
+// UpdateTaxYearConfig replaces every limit of the configuration of
+// config.TaxYear. The name and tax year are kept.
+func (r *taxDeductionConfigRepository) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	newVersion, err := r.updateIfVersion(ctx, "tax_year", *config.TaxYear, map[string]interface{}{
+		"personal_deduction":      config.PersonalDeduction,
+		"k_receipt_deduction_max": config.KReceiptDeductionMax,
+		"donation_deduction_max":  config.DonationDeductionMax,
+		"insurance_deduction_max": config.InsuranceDeductionMax,
+		"rmf_deduction_max":       config.RMFDeductionMax,
+		"ssf_deduction_max":       config.SSFDeductionMax,
+	}, version)
+	if !errors.Is(err, domains.ErrConfigVersionConflict) {
+		return newVersion, err
+	}
+
+	// No row matched: either the version is stale or the year has no
+	// configuration at all.
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&domains.TaxDeductionConfig{}).Where("tax_year = ?", *config.TaxYear).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, domains.ErrTaxYearConfigNotFound
+	}
+	return 0, domains.ErrConfigVersionConflict
+}
+
+func (r *taxDeductionConfigRepository) updateMainConfig(ctx context.Context, column string, amount float64, version int64) (int64, error) {
+	return r.updateIfVersion(ctx, "config_name", "MainConfig", map[string]interface{}{column: amount}, version)
+}
+
+// updateIfVersion applies updates to the configuration whose key column is
+// key and bumps the version in a single conditional UPDATE, so a concurrent
+// change makes it match no rows instead of being overwritten. For
+// domains.AnyConfigVersion there is no version condition and the new version
+// is read back with RETURNING.
+func (r *taxDeductionConfigRepository) updateIfVersion(ctx context.Context, keyColumn string, key interface{}, updates map[string]interface{}, version int64) (_ int64, err error) {
+	columns := make([]string, 0, len(updates))
+	for column := range updates {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	ctx, span := tracing.Start(ctx, "taxDeductionConfigRepository.Update",
+		attribute.String("config.column", strings.Join(columns, ",")),
+		attribute.Int64("config.version", version),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	updates["version"] = gorm.Expr("version + 1")
+	var result *gorm.DB
+	var updated domains.TaxDeductionConfig
+	if version == domains.AnyConfigVersion {
+		result = r.db.WithContext(ctx).Model(&updated).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}}}).
+			Where(keyColumn+" = ?", key).
+			Updates(updates)
+	} else {
+		result = r.db.WithContext(ctx).Model(&domains.TaxDeductionConfig{}).
+			Where(keyColumn+" = ? AND version = ?", key, version).
+			Updates(updates)
+	}
 	if result.Error != nil {
-		return result.Error
+		slog.ErrorContext(ctx, "Failed to update deduction configuration", "columns", columns, "error", result.Error)
+		return 0, result.Error
 	}
-	return nil
+	if result.RowsAffected == 0 {
+		slog.WarnContext(ctx, "Deduction configuration update lost to a concurrent change", "columns", columns, "version", version)
+		return 0, domains.ErrConfigVersionConflict
+	}
+	if version == domains.AnyConfigVersion {
+		return updated.Version, nil
+	}
+	return version + 1, nil
 }
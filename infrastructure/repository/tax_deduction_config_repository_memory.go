@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+)
+
+type inMemoryTaxDeductionConfigRepository struct {
+	mu       sync.RWMutex
+	config   domains.TaxDeductionConfig
+	taxYears map[int]domains.TaxDeductionConfig
+}
+
+// NewInMemoryTaxDeductionConfigRepository keeps the configuration in memory,
+// starting from the default MainConfig and no tax year configurations.
+// Nothing survives a restart, so it is meant for local development and tests.
+func NewInMemoryTaxDeductionConfigRepository() TaxDeductionConfigRepositoryInterface {
+	return &inMemoryTaxDeductionConfigRepository{
+		config:   domains.DefaultTaxDeductionConfig(),
+		taxYears: make(map[int]domains.TaxDeductionConfig),
+	}
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	config := r.config
+	return &config, nil
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := make([]domains.TaxDeductionConfig, 0, len(r.taxYears))
+	for _, config := range r.taxYears {
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return *configs[i].TaxYear < *configs[j].TaxYear })
+	return configs, nil
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	year := *config.TaxYear
+	if _, ok := r.taxYears[year]; ok {
+		return domains.ErrTaxYearConfigExists
+	}
+	config.ConfigName = domains.TaxYearConfigName(year)
+	config.Version = 1
+	stored := *config
+	stored.TaxYear = &year
+	r.taxYears[year] = stored
+	return nil
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	year := *config.TaxYear
+	stored, ok := r.taxYears[year]
+	if !ok {
+		return 0, domains.ErrTaxYearConfigNotFound
+	}
+	if version != domains.AnyConfigVersion && stored.Version != version {
+		return 0, domains.ErrConfigVersionConflict
+	}
+	stored.PersonalDeduction = config.PersonalDeduction
+	stored.KReceiptDeductionMax = config.KReceiptDeductionMax
+	stored.DonationDeductionMax = config.DonationDeductionMax
+	stored.InsuranceDeductionMax = config.InsuranceDeductionMax
+	stored.RMFDeductionMax = config.RMFDeductionMax
+	stored.SSFDeductionMax = config.SSFDeductionMax
+	stored.Version++
+	r.taxYears[year] = stored
+	return stored.Version, nil
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateIfVersion(version, func(config *domains.TaxDeductionConfig) {
+		config.PersonalDeduction = amount
+	})
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateIfVersion(version, func(config *domains.TaxDeductionConfig) {
+		config.KReceiptDeductionMax = amount
+	})
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateIfVersion(version, func(config *domains.TaxDeductionConfig) {
+		config.InsuranceDeductionMax = amount
+	})
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateIfVersion(version, func(config *domains.TaxDeductionConfig) {
+		config.RMFDeductionMax = amount
+	})
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	return r.updateIfVersion(version, func(config *domains.TaxDeductionConfig) {
+		config.SSFDeductionMax = amount
+	})
+}
+
+func (r *inMemoryTaxDeductionConfigRepository) updateIfVersion(version int64, update func(*domains.TaxDeductionConfig)) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if version != domains.AnyConfigVersion && r.config.Version != version {
+		return 0, domains.ErrConfigVersionConflict
+	}
+	update(&r.config)
+	r.config.Version++
+	return r.config.Version, nil
+}
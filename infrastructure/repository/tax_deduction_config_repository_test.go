@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -37,23 +39,24 @@ func TestGetConfig(t *testing.T) {
 	gdb, mock, cleanup := setupMock()
 	defer cleanup()
 
-	taxRepo := NewTaxDeductionConfigRepository(gdb)
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
 
 	expectedConfig := domains.TaxDeductionConfig{
 		ConfigName:           "MainConfig",
 		PersonalDeduction:    60000,
 		KReceiptDeductionMax: 50000,
 		DonationDeductionMax: 100000,
+		Version:              3,
 	}
 
-	rows := sqlmock.NewRows([]string{"config_name", "personal_deduction", "k_receipt_deduction_max", "donation_deduction_max"}).
-		AddRow(expectedConfig.ConfigName, expectedConfig.PersonalDeduction, expectedConfig.KReceiptDeductionMax, expectedConfig.DonationDeductionMax)
+	rows := sqlmock.NewRows([]string{"config_name", "personal_deduction", "k_receipt_deduction_max", "donation_deduction_max", "version"}).
+		AddRow(expectedConfig.ConfigName, expectedConfig.PersonalDeduction, expectedConfig.KReceiptDeductionMax, expectedConfig.DonationDeductionMax, expectedConfig.Version)
 
 	mock.ExpectQuery(`SELECT \* FROM "tax_deduction_configs" WHERE config_name = \$1 ORDER BY "tax_deduction_configs"\."config_name" LIMIT \$2`).
 		WithArgs("MainConfig", 1).
 		WillReturnRows(rows)
 
-	config, err := taxRepo.GetConfig()
+	config, err := taxRepo.GetConfig(context.Background())
 	assert.NoError(t, err)
 	assert.NotNil(t, config)
 	assert.Equal(t, expectedConfig, *config)
@@ -62,58 +65,231 @@ func TestGetConfig(t *testing.T) {
 }
 
 
+func TestGetTaxYearConfigs(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
+
+	taxYear := 2026
+	rows := sqlmock.NewRows([]string{"config_name", "personal_deduction", "k_receipt_deduction_max", "donation_deduction_max", "version", "tax_year"}).
+		AddRow("TaxYear2026", 70000.0, 50000.0, 100000.0, 1, taxYear)
+
+	mock.ExpectQuery(`SELECT \* FROM "tax_deduction_configs" WHERE tax_year IS NOT NULL ORDER BY tax_year`).
+		WillReturnRows(rows)
+
+	configs, err := taxRepo.GetTaxYearConfigs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domains.TaxDeductionConfig{{
+		ConfigName:           "TaxYear2026",
+		PersonalDeduction:    70000,
+		KReceiptDeductionMax: 50000,
+		DonationDeductionMax: 100000,
+		Version:              1,
+		TaxYear:              &taxYear,
+	}}, configs)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+
 func TestUpdatePersonalDeduction(t *testing.T) {
 	gdb, mock, cleanup := setupMock()
 	defer cleanup()
 
-	taxRepo := NewTaxDeductionConfigRepository(gdb)
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1 WHERE config_name = \$2`).
-		WithArgs(70000.0, "MainConfig").
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+		WithArgs(70000.0, "MainConfig", int64(1)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := taxRepo.UpdatePersonalDeduction(70000)
+	version, err := taxRepo.UpdatePersonalDeduction(context.Background(), 70000, 1)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1 WHERE config_name = \$2`).
-		WithArgs(70000.0, "MainConfig").
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+		WithArgs(70000.0, "MainConfig", int64(1)).
 		WillReturnError(gorm.ErrInvalidData)
 	mock.ExpectRollback()
 
-	err = taxRepo.UpdatePersonalDeduction(70000)
+	_, err = taxRepo.UpdatePersonalDeduction(context.Background(), 70000, 1)
 	assert.Error(t, err)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestUpdatePersonalDeduction_VersionConflict(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+		WithArgs(70000.0, "MainConfig", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	_, err := taxRepo.UpdatePersonalDeduction(context.Background(), 70000, 1)
+	assert.ErrorIs(t, err, domains.ErrConfigVersionConflict)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePersonalDeduction_AnyVersion(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1,"version"=version \+ 1 WHERE config_name = \$2 RETURNING "version"`).
+		WithArgs(70000.0, "MainConfig").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(7)))
+	mock.ExpectCommit()
+
+	version, err := taxRepo.UpdatePersonalDeduction(context.Background(), 70000, domains.AnyConfigVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), version)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTaxYearConfig_NotFound(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
+
+	year := 2025
+	config := domains.DefaultTaxDeductionConfig()
+	config.TaxYear = &year
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "donation_deduction_max"=\$1,"insurance_deduction_max"=\$2,"k_receipt_deduction_max"=\$3,"personal_deduction"=\$4,"rmf_deduction_max"=\$5,"ssf_deduction_max"=\$6,"version"=version \+ 1 WHERE tax_year = \$7 AND version = \$8`).
+		WithArgs(100000.0, 100000.0, 50000.0, 60000.0, 500000.0, 200000.0, 2025, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "tax_deduction_configs" WHERE tax_year = \$1`).
+		WithArgs(2025).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	_, err := taxRepo.UpdateTaxYearConfig(context.Background(), &config, 1)
+	assert.ErrorIs(t, err, domains.ErrTaxYearConfigNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateKReceiptDeductionMax(t *testing.T) {
 	gdb, mock, cleanup := setupMock()
 	defer cleanup()
 
-	taxRepo := NewTaxDeductionConfigRepository(gdb)
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Second)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "k_receipt_deduction_max"=\$1 WHERE config_name = \$2`).
-		WithArgs(45000.0, "MainConfig").
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "k_receipt_deduction_max"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+		WithArgs(45000.0, "MainConfig", int64(1)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := taxRepo.UpdateKReceiptDeductionMax(45000)
+	version, err := taxRepo.UpdateKReceiptDeductionMax(context.Background(), 45000, 1)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "k_receipt_deduction_max"=\$1 WHERE config_name = \$2`).
-		WithArgs(45000.0, "MainConfig").
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "k_receipt_deduction_max"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+		WithArgs(45000.0, "MainConfig", int64(1)).
 		WillReturnError(gorm.ErrInvalidData)
 	mock.ExpectRollback()
 
-	err = taxRepo.UpdateKReceiptDeductionMax(45000)
+	_, err = taxRepo.UpdateKReceiptDeductionMax(context.Background(), 45000, 1)
 	assert.Error(t, err)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestUpdateAllowanceDeductionMax(t *testing.T) {
+	tests := []struct {
+		column string
+		update func(TaxDeductionConfigRepositoryInterface) (int64, error)
+	}{
+		{"insurance_deduction_max", func(r TaxDeductionConfigRepositoryInterface) (int64, error) {
+			return r.UpdateInsuranceDeductionMax(context.Background(), 90000, 1)
+		}},
+		{"rmf_deduction_max", func(r TaxDeductionConfigRepositoryInterface) (int64, error) {
+			return r.UpdateRMFDeductionMax(context.Background(), 90000, 1)
+		}},
+		{"ssf_deduction_max", func(r TaxDeductionConfigRepositoryInterface) (int64, error) {
+			return r.UpdateSSFDeductionMax(context.Background(), 90000, 1)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			gdb, mock, cleanup := setupMock()
+			defer cleanup()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "`+tt.column+`"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+				WithArgs(90000.0, "MainConfig", int64(1)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+
+			version, err := tt.update(NewTaxDeductionConfigRepository(gdb, time.Second))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), version)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 
+
+func TestGetConfig_CancelledContextAbortsQuery(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	taxRepo := NewTaxDeductionConfigRepository(gdb, time.Minute)
+
+	mock.ExpectQuery(`SELECT \* FROM "tax_deduction_configs" WHERE config_name = \$1`).
+		WithArgs("MainConfig", 1).
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"config_name"}).AddRow("MainConfig"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	config, err := taxRepo.GetConfig(ctx)
+	// sqlmock reports its own cancellation error; what matters is that the
+	// call returns as soon as the request is cancelled instead of waiting for
+	// the query to finish.
+	assert.Error(t, err)
+	assert.Nil(t, config)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestUpdatePersonalDeduction_QueryTimeout(t *testing.T) {
+	gdb, mock, cleanup := setupMock()
+	defer cleanup()
+
+	taxRepo := NewTaxDeductionConfigRepository(gdb, 20*time.Millisecond)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tax_deduction_configs" SET "personal_deduction"=\$1,"version"=version \+ 1 WHERE config_name = \$2 AND version = \$3`).
+		WithArgs(70000.0, "MainConfig", int64(1)).
+		WillDelayFor(time.Minute).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	start := time.Now()
+	_, err := taxRepo.UpdatePersonalDeduction(context.Background(), 70000, 1)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
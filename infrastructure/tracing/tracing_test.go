@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartAndEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(sdktrace.NewTracerProvider()) })
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("connection refused"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "child", spans[0].Name())
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "connection refused", spans[0].Status().Description)
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	}
+}
+
+func TestInit(t *testing.T) {
+	shutdown, err := Init(context.Background(), "none", "ktax")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	// W3C trace context is propagated even when spans are not exported.
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+
+	_, err = Init(context.Background(), "zipkin", "ktax")
+	assert.EqualError(t, err, `unsupported traces exporter "zipkin"`)
+}
@@ -1,14 +1,27 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/thitiphum-bluesage/assessment-tax/applications/services/admin"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
 	"github.com/thitiphum-bluesage/assessment-tax/utilities"
 )
 
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
 type AdminController struct {
 	service admin.AdminServiceInterface
 }
@@ -19,32 +32,101 @@ func NewAdminController(service admin.AdminServiceInterface) *AdminController {
 	}
 }
 
+// GetDeductionConfig returns the current deduction configuration
+// @Summary Get deduction configuration
+// @Description Get the current deduction limits. The ETag response header carries the configuration version to send as If-Match when updating.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schemas.DeductionConfigResponse
+// @Header 200 {string} ETag "Configuration version"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /admin/deductions [get]
+// @Router /v1/admin/deductions [get]
+func (ac *AdminController) GetDeductionConfig(c echo.Context) error {
+	config, err := ac.service.GetConfig(c.Request().Context())
+	if err != nil {
+		return internalError(err)
+	}
+
+	c.Response().Header().Set(headerETag, configETag(config.Version))
+	return c.JSON(http.StatusOK, schemas.DeductionConfigResponse{
+		PersonalDeduction: config.PersonalDeduction,
+		KReceipt:          config.KReceiptDeductionMax,
+		Donation:          config.DonationDeductionMax,
+	})
+}
+
+// GetDeductionConfigV2 returns the current deduction configuration with the
+// insurance, RMF and SSF caps
+// @Summary Get deduction configuration
+// @Description Get the current deduction limits, including the insurance, RMF and SSF caps. The ETag response header carries the configuration version to send as If-Match when updating.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schemas.DeductionConfigV2Response
+// @Header 200 {string} ETag "Configuration version"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions [get]
+func (ac *AdminController) GetDeductionConfigV2(c echo.Context) error {
+	config, err := ac.service.GetConfig(c.Request().Context())
+	if err != nil {
+		return internalError(err)
+	}
+
+	c.Response().Header().Set(headerETag, configETag(config.Version))
+	return c.JSON(http.StatusOK, schemas.DeductionConfigV2Response{
+		DeductionConfigResponse: schemas.DeductionConfigResponse{
+			PersonalDeduction: config.PersonalDeduction,
+			KReceipt:          config.KReceiptDeductionMax,
+			Donation:          config.DonationDeductionMax,
+		},
+		Insurance: config.InsuranceDeductionMax,
+		RMF:       config.RMFDeductionMax,
+		SSF:       config.SSFDeductionMax,
+	})
+}
+
 // UpdatePersonalDeduction updates the personal tax deduction amount
 // @Summary Update personal deduction
-// @Description Update the personal deduction for a tax payer
+// @Description Update the personal deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Param If-Match header string true "ETag of the configuration being updated, or * for the current version"
 // @Param request body schemas.UpdatePersonalDeductionRequest true "Update Personal Deduction Request"
 // @Success 200 {object} schemas.UpdatePersonalDeductionResponse
+// @Header 200 {string} ETag "New configuration version"
 // @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 412 {object} schemas.ErrorResponse "Configuration was changed by another request"
+// @Failure 428 {object} schemas.ErrorResponse "If-Match header is missing"
 // @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
 // @Security basicAuth
 // @Router /admin/deductions/personal [post]
+// @Router /v1/admin/deductions/personal [post]
+// @Router /v2/admin/deductions/personal [post]
 func (ac *AdminController) UpdatePersonalDeduction(c echo.Context) error {
 	var req schemas.UpdatePersonalDeductionRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid input data")
+		return bindError(c.Request().Context(), err)
 	}
 
 	if err := utilities.ValidateUpdatePersonalDeductionRequest(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		return validationError(c.Request().Context(), err)
+	}
+
+	version, err := ac.ifMatchVersion(c)
+	if err != nil {
+		return err
 	}
 
-	if err := ac.service.UpdatePersonalDeduction(*req.Amount); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	newVersion, err := ac.service.UpdatePersonalDeduction(c.Request().Context(), *req.Amount, version)
+	if err != nil {
+		return configUpdateError(c.Request().Context(), err)
 	}
 
+	slog.InfoContext(c.Request().Context(), "Updated personal deduction", "amount", *req.Amount, "version", newVersion)
+	c.Response().Header().Set(headerETag, configETag(newVersion))
 	return c.JSON(http.StatusOK, schemas.UpdatePersonalDeductionResponse{
 		PersonalDeduction: *req.Amount,
 	})
@@ -52,29 +134,357 @@ func (ac *AdminController) UpdatePersonalDeduction(c echo.Context) error {
 
 // UpdateKReceiptDeduction updates the K receipt deduction amount
 // @Summary Update K receipt deduction
-// @Description Update the K receipt deduction for a tax payer
+// @Description Update the K receipt deduction for a tax payer. If-Match must carry the ETag from GET /admin/deductions.
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Param If-Match header string true "ETag of the configuration being updated, or * for the current version"
 // @Param request body schemas.UpdateKReceiptRequest true "Update K Receipt Deduction Request"
 // @Success 200 {object} schemas.UpdateKReceiptResponse
+// @Header 200 {string} ETag "New configuration version"
 // @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 412 {object} schemas.ErrorResponse "Configuration was changed by another request"
+// @Failure 428 {object} schemas.ErrorResponse "If-Match header is missing"
 // @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
 // @Security basicAuth
 // @Router /admin/deductions/k-receipt [post]
+// @Router /v1/admin/deductions/k-receipt [post]
+// @Router /v2/admin/deductions/k-receipt [post]
 func (ac *AdminController) UpdateKReceiptDeduction(c echo.Context) error {
 	var req schemas.UpdateKReceiptRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid input data")
+		return bindError(c.Request().Context(), err)
 	}
 
 	if err := utilities.ValidateUpdateKReceiptRequest(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		return validationError(c.Request().Context(), err)
 	}
 
-	if err := ac.service.UpdateKReceiptDeductionMax(*req.Amount); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	version, err := ac.ifMatchVersion(c)
+	if err != nil {
+		return err
 	}
 
+	newVersion, err := ac.service.UpdateKReceiptDeductionMax(c.Request().Context(), *req.Amount, version)
+	if err != nil {
+		return configUpdateError(c.Request().Context(), err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Updated k-receipt deduction limit", "amount", *req.Amount, "version", newVersion)
+	c.Response().Header().Set(headerETag, configETag(newVersion))
 	return c.JSON(http.StatusOK, schemas.UpdateKReceiptResponse{KReceipt: *req.Amount})
 }
+
+// UpdateInsuranceDeduction updates the insurance deduction limit
+// @Summary Update insurance deduction
+// @Description Update the insurance deduction limit. If-Match must carry the ETag from GET /v2/admin/deductions.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "ETag of the configuration being updated, or * for the current version"
+// @Param request body schemas.UpdateInsuranceRequest true "Update Insurance Deduction Request"
+// @Success 200 {object} schemas.UpdateInsuranceResponse
+// @Header 200 {string} ETag "New configuration version"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 412 {object} schemas.ErrorResponse "Configuration was changed by another request"
+// @Failure 428 {object} schemas.ErrorResponse "If-Match header is missing"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions/insurance [post]
+func (ac *AdminController) UpdateInsuranceDeduction(c echo.Context) error {
+	var req schemas.UpdateInsuranceRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c.Request().Context(), err)
+	}
+
+	if err := utilities.ValidateUpdateInsuranceRequest(&req); err != nil {
+		return validationError(c.Request().Context(), err)
+	}
+
+	version, err := ac.ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	newVersion, err := ac.service.UpdateInsuranceDeductionMax(c.Request().Context(), *req.Amount, version)
+	if err != nil {
+		return configUpdateError(c.Request().Context(), err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Updated insurance deduction limit", "amount", *req.Amount, "version", newVersion)
+	c.Response().Header().Set(headerETag, configETag(newVersion))
+	return c.JSON(http.StatusOK, schemas.UpdateInsuranceResponse{Insurance: *req.Amount})
+}
+
+// UpdateRMFDeduction updates the RMF deduction limit
+// @Summary Update RMF deduction
+// @Description Update the RMF (Retirement Mutual Fund) deduction limit. If-Match must carry the ETag from GET /v2/admin/deductions.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "ETag of the configuration being updated, or * for the current version"
+// @Param request body schemas.UpdateRMFRequest true "Update RMF Deduction Request"
+// @Success 200 {object} schemas.UpdateRMFResponse
+// @Header 200 {string} ETag "New configuration version"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 412 {object} schemas.ErrorResponse "Configuration was changed by another request"
+// @Failure 428 {object} schemas.ErrorResponse "If-Match header is missing"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions/rmf [post]
+func (ac *AdminController) UpdateRMFDeduction(c echo.Context) error {
+	var req schemas.UpdateRMFRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c.Request().Context(), err)
+	}
+
+	if err := utilities.ValidateUpdateRMFRequest(&req); err != nil {
+		return validationError(c.Request().Context(), err)
+	}
+
+	version, err := ac.ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	newVersion, err := ac.service.UpdateRMFDeductionMax(c.Request().Context(), *req.Amount, version)
+	if err != nil {
+		return configUpdateError(c.Request().Context(), err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Updated rmf deduction limit", "amount", *req.Amount, "version", newVersion)
+	c.Response().Header().Set(headerETag, configETag(newVersion))
+	return c.JSON(http.StatusOK, schemas.UpdateRMFResponse{RMF: *req.Amount})
+}
+
+// UpdateSSFDeduction updates the SSF deduction limit
+// @Summary Update SSF deduction
+// @Description Update the SSF (Super Savings Fund) deduction limit. If-Match must carry the ETag from GET /v2/admin/deductions.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "ETag of the configuration being updated, or * for the current version"
+// @Param request body schemas.UpdateSSFRequest true "Update SSF Deduction Request"
+// @Success 200 {object} schemas.UpdateSSFResponse
+// @Header 200 {string} ETag "New configuration version"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 412 {object} schemas.ErrorResponse "Configuration was changed by another request"
+// @Failure 428 {object} schemas.ErrorResponse "If-Match header is missing"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions/ssf [post]
+func (ac *AdminController) UpdateSSFDeduction(c echo.Context) error {
+	var req schemas.UpdateSSFRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c.Request().Context(), err)
+	}
+
+	if err := utilities.ValidateUpdateSSFRequest(&req); err != nil {
+		return validationError(c.Request().Context(), err)
+	}
+
+	version, err := ac.ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	newVersion, err := ac.service.UpdateSSFDeductionMax(c.Request().Context(), *req.Amount, version)
+	if err != nil {
+		return configUpdateError(c.Request().Context(), err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Updated ssf deduction limit", "amount", *req.Amount, "version", newVersion)
+	c.Response().Header().Set(headerETag, configETag(newVersion))
+	return c.JSON(http.StatusOK, schemas.UpdateSSFResponse{SSF: *req.Amount})
+}
+
+// ListTaxYearConfigs returns the deduction configurations of every tax year
+// @Summary List tax year deduction configurations
+// @Description List the deduction configurations pinned to a tax year, ordered by year. Projections use them for the years they cover.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schemas.TaxYearConfigsResponse
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions/tax-years [get]
+func (ac *AdminController) ListTaxYearConfigs(c echo.Context) error {
+	configs, err := ac.service.GetTaxYearConfigs(c.Request().Context())
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := schemas.TaxYearConfigsResponse{TaxYears: make([]schemas.TaxYearConfigResponse, len(configs))}
+	for i := range configs {
+		resp.TaxYears[i] = taxYearConfigResponse(&configs[i])
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CreateTaxYearConfig creates the deduction configuration of a tax year
+// @Summary Create tax year deduction configuration
+// @Description Create the deduction configuration of a tax year at version 1. Limits that are left out take their default. The ETag response header carries the version to send as If-Match when updating.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param year path int true "Tax year"
+// @Param request body schemas.TaxYearConfigRequest true "Tax Year Configuration Request"
+// @Success 201 {object} schemas.TaxYearConfigResponse
+// @Header 201 {string} ETag "Configuration version"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 409 {object} schemas.ErrorResponse "The tax year already has a configuration"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions/tax-years/{year} [post]
+func (ac *AdminController) CreateTaxYearConfig(c echo.Context) error {
+	config, err := bindTaxYearConfig(c)
+	if err != nil {
+		return err
+	}
+
+	if err := ac.service.CreateTaxYearConfig(c.Request().Context(), config); err != nil {
+		return configUpdateError(c.Request().Context(), err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Created tax year deduction configuration", "taxYear", *config.TaxYear)
+	c.Response().Header().Set(headerETag, configETag(config.Version))
+	return c.JSON(http.StatusCreated, taxYearConfigResponse(config))
+}
+
+// UpdateTaxYearConfig replaces the deduction configuration of a tax year
+// @Summary Update tax year deduction configuration
+// @Description Replace every limit of the deduction configuration of a tax year. Limits that are left out take their default. If-Match must carry the ETag from creating the configuration or from the last update.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param year path int true "Tax year"
+// @Param If-Match header string true "ETag of the configuration being updated, or * for the current version"
+// @Param request body schemas.TaxYearConfigRequest true "Tax Year Configuration Request"
+// @Success 200 {object} schemas.TaxYearConfigResponse
+// @Header 200 {string} ETag "New configuration version"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 404 {object} schemas.ErrorResponse "The tax year has no configuration"
+// @Failure 412 {object} schemas.ErrorResponse "Configuration was changed by another request"
+// @Failure 428 {object} schemas.ErrorResponse "If-Match header is missing"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Security basicAuth
+// @Router /v2/admin/deductions/tax-years/{year} [put]
+func (ac *AdminController) UpdateTaxYearConfig(c echo.Context) error {
+	config, err := bindTaxYearConfig(c)
+	if err != nil {
+		return err
+	}
+
+	version, err := ac.ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	newVersion, err := ac.service.UpdateTaxYearConfig(c.Request().Context(), config, version)
+	if err != nil {
+		return configUpdateError(c.Request().Context(), err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Updated tax year deduction configuration", "taxYear", *config.TaxYear, "version", newVersion)
+	config.Version = newVersion
+	c.Response().Header().Set(headerETag, configETag(newVersion))
+	return c.JSON(http.StatusOK, taxYearConfigResponse(config))
+}
+
+// bindTaxYearConfig reads and checks the tax year in the path and the limits
+// in the body, filling in the default of every limit that is left out.
+func bindTaxYearConfig(c echo.Context) (*domains.TaxDeductionConfig, error) {
+	var req schemas.TaxYearConfigRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, bindError(c.Request().Context(), err)
+	}
+
+	if err := utilities.ValidateTaxYearConfigRequest(c.Param("year"), &req); err != nil {
+		return nil, validationError(c.Request().Context(), err)
+	}
+
+	year, _ := strconv.Atoi(c.Param("year"))
+	config := domains.DefaultTaxDeductionConfig()
+	config.ConfigName = domains.TaxYearConfigName(year)
+	config.TaxYear = &year
+	for _, limit := range []struct {
+		value  *float64
+		target *float64
+	}{
+		{req.PersonalDeduction, &config.PersonalDeduction},
+		{req.KReceipt, &config.KReceiptDeductionMax},
+		{req.Insurance, &config.InsuranceDeductionMax},
+		{req.RMF, &config.RMFDeductionMax},
+		{req.SSF, &config.SSFDeductionMax},
+	} {
+		if limit.value != nil {
+			*limit.target = *limit.value
+		}
+	}
+	return &config, nil
+}
+
+func taxYearConfigResponse(config *domains.TaxDeductionConfig) schemas.TaxYearConfigResponse {
+	return schemas.TaxYearConfigResponse{
+		TaxYear:    *config.TaxYear,
+		ConfigName: config.ConfigName,
+		Version:    config.Version,
+		DeductionConfigV2Response: schemas.DeductionConfigV2Response{
+			DeductionConfigResponse: schemas.DeductionConfigResponse{
+				PersonalDeduction: config.PersonalDeduction,
+				KReceipt:          config.KReceiptDeductionMax,
+				Donation:          config.DonationDeductionMax,
+			},
+			Insurance: config.InsuranceDeductionMax,
+			RMF:       config.RMFDeductionMax,
+			SSF:       config.SSFDeductionMax,
+		},
+	}
+}
+
+func configETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatchVersion reads the configuration version the client last saw from the
+// If-Match header. Updates without it are rejected so that a client cannot
+// overwrite a change it has not seen. "*" matches whatever version is current
+// when the update runs, so it is passed on as domains.AnyConfigVersion rather
+// than resolved to a version that may be stale by then. If-Match uses strong
+// comparison, so weak tags such as W/"3" never match.
+func (ac *AdminController) ifMatchVersion(c echo.Context) (int64, error) {
+	ctx := c.Request().Context()
+	ifMatch := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if ifMatch == "" {
+		return 0, apiError(http.StatusPreconditionRequired, schemas.ErrorCodePreconditionRequired,
+			i18n.Translate(ctx, "If-Match header is required"))
+	}
+	if ifMatch == "*" {
+		return domains.AnyConfigVersion, nil
+	}
+
+	preconditionFailed := apiError(http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict,
+		i18n.Translate(ctx, domains.ErrConfigVersionConflict.Error()))
+	if len(ifMatch) < 2 || ifMatch[0] != '"' || ifMatch[len(ifMatch)-1] != '"' {
+		return 0, preconditionFailed
+	}
+	version, err := strconv.ParseInt(ifMatch[1:len(ifMatch)-1], 10, 64)
+	if err != nil {
+		return 0, preconditionFailed
+	}
+	return version, nil
+}
+
+func configUpdateError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, domains.ErrConfigVersionConflict):
+		return apiError(http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict,
+			i18n.Translate(ctx, domains.ErrConfigVersionConflict.Error()))
+	case errors.Is(err, domains.ErrTaxYearConfigNotFound):
+		return apiError(http.StatusNotFound, schemas.ErrorCodeNotFound,
+			i18n.Translate(ctx, domains.ErrTaxYearConfigNotFound.Error()))
+	case errors.Is(err, domains.ErrTaxYearConfigExists):
+		return apiError(http.StatusConflict, schemas.ErrorCodeTaxYearConfigExists,
+			i18n.Translate(ctx, domains.ErrTaxYearConfigExists.Error()))
+	}
+	return internalError(err)
+}
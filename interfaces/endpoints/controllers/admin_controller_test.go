@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
 )
 
@@ -17,16 +19,55 @@ type MockAdminService struct {
 	mock.Mock
 }
 
-func (m *MockAdminService) UpdatePersonalDeduction(amount float64) error {
-	args := m.Called(amount)
-	return args.Error(0)
+func (m *MockAdminService) GetConfig(ctx context.Context) (*domains.TaxDeductionConfig, error) {
+	args := m.Called()
+	if config, ok := args.Get(0).(*domains.TaxDeductionConfig); ok {
+		return config, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAdminService) UpdatePersonalDeduction(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAdminService) UpdateKReceiptDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAdminService) UpdateInsuranceDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAdminService) UpdateRMFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockAdminService) UpdateKReceiptDeductionMax(amount float64) error {
-	args := m.Called(amount)
+func (m *MockAdminService) UpdateSSFDeductionMax(ctx context.Context, amount float64, version int64) (int64, error) {
+	args := m.Called(amount, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAdminService) GetTaxYearConfigs(ctx context.Context) ([]domains.TaxDeductionConfig, error) {
+	args := m.Called()
+	configs, _ := args.Get(0).([]domains.TaxDeductionConfig)
+	return configs, args.Error(1)
+}
+
+func (m *MockAdminService) CreateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig) error {
+	args := m.Called(config)
 	return args.Error(0)
 }
 
+func (m *MockAdminService) UpdateTaxYearConfig(ctx context.Context, config *domains.TaxDeductionConfig, version int64) (int64, error) {
+	args := m.Called(config, version)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestAdminController_UpdatePersonalDeduction_ValidInput(t *testing.T) {
 	// Create a new Echo instance
 	e := echo.New()
@@ -37,6 +78,7 @@ func TestAdminController_UpdatePersonalDeduction_ValidInput(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/admin/personal-deduction", strings.NewReader(string(jsonBody)))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", `"1"`)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
@@ -44,7 +86,7 @@ func TestAdminController_UpdatePersonalDeduction_ValidInput(t *testing.T) {
 	mockService := new(MockAdminService)
 
 	// Set up the mock expectation
-	mockService.On("UpdatePersonalDeduction", validAmount).Return(nil)
+	mockService.On("UpdatePersonalDeduction", validAmount, int64(1)).Return(int64(2), nil)
 
 	// Create a new AdminController instance with the mock service
 	controller := &AdminController{
@@ -53,6 +95,7 @@ func TestAdminController_UpdatePersonalDeduction_ValidInput(t *testing.T) {
 
 	if assert.NoError(t, controller.UpdatePersonalDeduction(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
 		var resp schemas.UpdatePersonalDeductionResponse
 		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
 			assert.Equal(t, validAmount, resp.PersonalDeduction)
@@ -96,6 +139,7 @@ func TestAdminController_UpdateKReceiptDeduction_ValidInput(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/admin/k-receipt-deduction", strings.NewReader(string(jsonBody)))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", `"1"`)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
@@ -103,7 +147,7 @@ func TestAdminController_UpdateKReceiptDeduction_ValidInput(t *testing.T) {
 	mockService := new(MockAdminService)
 
 	// Set up the mock expectation
-	mockService.On("UpdateKReceiptDeductionMax", validAmount).Return(nil)
+	mockService.On("UpdateKReceiptDeductionMax", validAmount, int64(1)).Return(int64(2), nil)
 
 	// Create a new AdminController instance with the mock service
 	controller := &AdminController{
@@ -112,6 +156,7 @@ func TestAdminController_UpdateKReceiptDeduction_ValidInput(t *testing.T) {
 
 	if assert.NoError(t, controller.UpdateKReceiptDeduction(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
 		var resp schemas.UpdateKReceiptResponse
 		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
 			assert.Equal(t, validAmount, resp.KReceipt)
@@ -144,3 +189,260 @@ func TestAdminController_UpdateKReceiptDeduction_InvalidInput(t *testing.T) {
 	assert.Contains(t, err.Error(), "amount for k-receipt must be between 1 and 100,000")
 	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
 }
+
+func TestAdminController_GetDeductionConfig(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/deductions", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockService := new(MockAdminService)
+	mockService.On("GetConfig").Return(&domains.TaxDeductionConfig{
+		ConfigName:           "MainConfig",
+		PersonalDeduction:    60000,
+		KReceiptDeductionMax: 50000,
+		DonationDeductionMax: 100000,
+		Version:              7,
+	}, nil)
+
+	controller := &AdminController{service: mockService}
+
+	if assert.NoError(t, controller.GetDeductionConfig(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"7"`, rec.Header().Get("ETag"))
+		var resp schemas.DeductionConfigResponse
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, schemas.DeductionConfigResponse{PersonalDeduction: 60000, KReceipt: 50000, Donation: 100000}, resp)
+		}
+	}
+}
+
+func TestAdminController_GetDeductionConfigV2(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/admin/deductions", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockService := new(MockAdminService)
+	config := domains.DefaultTaxDeductionConfig()
+	mockService.On("GetConfig").Return(&config, nil)
+
+	controller := &AdminController{service: mockService}
+
+	if assert.NoError(t, controller.GetDeductionConfigV2(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"personalDeduction":60000,"kReceipt":50000,"donation":100000,"insurance":100000,"rmf":500000,"ssf":200000}`, rec.Body.String())
+	}
+}
+
+func TestAdminController_UpdateRMFDeduction(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       float64
+		expectedCode int
+	}{
+		{"Within the cap", 400000, http.StatusOK},
+		{"Zero", 0, http.StatusBadRequest},
+		{"Above the cap", 500001, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			jsonBody, _ := json.Marshal(schemas.UpdateRMFRequest{Amount: &tt.amount})
+			req := httptest.NewRequest(http.MethodPost, "/v2/admin/deductions/rmf", strings.NewReader(string(jsonBody)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("If-Match", `"3"`)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockAdminService)
+			if tt.expectedCode == http.StatusOK {
+				mockService.On("UpdateRMFDeductionMax", tt.amount, int64(3)).Return(int64(4), nil)
+			}
+			controller := &AdminController{service: mockService}
+
+			err := controller.UpdateRMFDeduction(c)
+			if tt.expectedCode == http.StatusOK {
+				if assert.NoError(t, err) {
+					assert.Equal(t, `"4"`, rec.Header().Get("ETag"))
+					assert.JSONEq(t, `{"rmf":400000}`, rec.Body.String())
+				}
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tt.expectedCode, err.(*echo.HTTPError).Code)
+				assert.Contains(t, err.Error(), "amount for rmf must be between 1 and 500,000")
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminController_UpdatePersonalDeduction_Preconditions(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      string
+		serviceErr   error
+		expectedCode int
+		errorCode    string
+	}{
+		{"Missing If-Match", "", nil, http.StatusPreconditionRequired, schemas.ErrorCodePreconditionRequired},
+		{"Malformed If-Match", "not-a-version", nil, http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict},
+		{"Unquoted If-Match", "1", nil, http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict},
+		{"Weak If-Match", `W/"1"`, nil, http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict},
+		{"Changed by someone else", `"1"`, domains.ErrConfigVersionConflict, http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			amount := 50000.0
+			jsonBody, _ := json.Marshal(schemas.UpdatePersonalDeductionRequest{Amount: &amount})
+			req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", strings.NewReader(string(jsonBody)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockAdminService)
+			if tt.serviceErr != nil {
+				mockService.On("UpdatePersonalDeduction", amount, int64(1)).Return(int64(0), tt.serviceErr)
+			}
+			controller := &AdminController{service: mockService}
+
+			err := controller.UpdatePersonalDeduction(c)
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.expectedCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tt.errorCode, err.(*echo.HTTPError).Message.(schemas.ErrorResponse).Code)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminController_UpdatePersonalDeduction_IfMatchAny(t *testing.T) {
+	e := echo.New()
+	amount := 50000.0
+	jsonBody, _ := json.Marshal(schemas.UpdatePersonalDeductionRequest{Amount: &amount})
+	req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", strings.NewReader(string(jsonBody)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+
+	mockService := new(MockAdminService)
+	mockService.On("UpdatePersonalDeduction", amount, domains.AnyConfigVersion).Return(int64(4), nil)
+	controller := &AdminController{service: mockService}
+
+	if assert.NoError(t, controller.UpdatePersonalDeduction(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"4"`, rec.Header().Get("ETag"))
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestAdminController_CreateTaxYearConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		year         string
+		body         string
+		serviceErr   error
+		expectedCode int
+		errorCode    string
+	}{
+		{"Created with defaults", "2025", `{"personalDeduction":70000,"rmf":400000}`, nil, http.StatusCreated, ""},
+		{"Already configured", "2025", `{}`, domains.ErrTaxYearConfigExists, http.StatusConflict, schemas.ErrorCodeTaxYearConfigExists},
+		{"Year out of range", "1999", `{}`, nil, http.StatusBadRequest, schemas.ErrorCodeValidationFailed},
+		{"Year not a number", "next", `{}`, nil, http.StatusBadRequest, schemas.ErrorCodeValidationFailed},
+		{"Limit above the cap", "2025", `{"ssf":200001}`, nil, http.StatusBadRequest, schemas.ErrorCodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/v2/admin/deductions/tax-years/"+tt.year, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("year")
+			c.SetParamValues(tt.year)
+
+			mockService := new(MockAdminService)
+			if tt.expectedCode != http.StatusBadRequest {
+				mockService.On("CreateTaxYearConfig", mock.AnythingOfType("*domains.TaxDeductionConfig")).
+					Run(func(args mock.Arguments) { args.Get(0).(*domains.TaxDeductionConfig).Version = 1 }).
+					Return(tt.serviceErr)
+			}
+			controller := &AdminController{service: mockService}
+
+			err := controller.CreateTaxYearConfig(c)
+			if tt.expectedCode == http.StatusCreated {
+				if assert.NoError(t, err) {
+					assert.Equal(t, http.StatusCreated, rec.Code)
+					assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+					assert.JSONEq(t, `{"taxYear":2025,"configName":"TaxYear2025","version":1,"personalDeduction":70000,"kReceipt":50000,"donation":100000,"insurance":100000,"rmf":400000,"ssf":200000}`, rec.Body.String())
+				}
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tt.expectedCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tt.errorCode, err.(*echo.HTTPError).Message.(schemas.ErrorResponse).Code)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminController_UpdateTaxYearConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      string
+		serviceErr   error
+		expectedCode int
+		errorCode    string
+	}{
+		{"Updated", `"1"`, nil, http.StatusOK, ""},
+		{"Missing If-Match", "", nil, http.StatusPreconditionRequired, schemas.ErrorCodePreconditionRequired},
+		{"Changed by someone else", `"1"`, domains.ErrConfigVersionConflict, http.StatusPreconditionFailed, schemas.ErrorCodeConfigVersionConflict},
+		{"Not configured", `"1"`, domains.ErrTaxYearConfigNotFound, http.StatusNotFound, schemas.ErrorCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/v2/admin/deductions/tax-years/2025", strings.NewReader(`{"kReceipt":40000}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("year")
+			c.SetParamValues("2025")
+
+			mockService := new(MockAdminService)
+			if tt.ifMatch != "" {
+				matchesYear := mock.MatchedBy(func(config *domains.TaxDeductionConfig) bool {
+					return *config.TaxYear == 2025 && config.KReceiptDeductionMax == 40000
+				})
+				newVersion := int64(2)
+				if tt.serviceErr != nil {
+					newVersion = 0
+				}
+				mockService.On("UpdateTaxYearConfig", matchesYear, int64(1)).Return(newVersion, tt.serviceErr)
+			}
+			controller := &AdminController{service: mockService}
+
+			err := controller.UpdateTaxYearConfig(c)
+			if tt.expectedCode == http.StatusOK {
+				if assert.NoError(t, err) {
+					assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+					assert.JSONEq(t, `{"taxYear":2025,"configName":"TaxYear2025","version":2,"personalDeduction":60000,"kReceipt":40000,"donation":100000,"insurance":100000,"rmf":500000,"ssf":200000}`, rec.Body.String())
+				}
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tt.expectedCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tt.errorCode, err.(*echo.HTTPError).Message.(schemas.ErrorResponse).Code)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
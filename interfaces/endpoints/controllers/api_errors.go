@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"github.com/thitiphum-bluesage/assessment-tax/utilities"
+)
+
+// apiError returns an HTTP error whose body is the structured error envelope
+// rather than a bare message.
+func apiError(status int, code, message string, fields ...schemas.FieldError) *echo.HTTPError {
+	return echo.NewHTTPError(status, schemas.ErrorResponse{Code: code, Message: message, Errors: fields})
+}
+
+func internalError(err error) *echo.HTTPError {
+	return apiError(http.StatusInternalServerError, schemas.ErrorCodeInternal, err.Error())
+}
+
+// validationError reports every field a request failed validation on, in the
+// language of the client.
+func validationError(ctx context.Context, err error) *echo.HTTPError {
+	var validationErr *utilities.ValidationError
+	if errors.As(err, &validationErr) {
+		validationErr = validationErr.Localize(i18n.Printer(ctx))
+		return apiError(http.StatusBadRequest, schemas.ErrorCodeValidationFailed, validationErr.Message, validationErr.Fields...)
+	}
+	return apiError(http.StatusBadRequest, schemas.ErrorCodeValidationFailed, err.Error())
+}
+
+// bindError reports a request body that could not be decoded. When a field
+// has the wrong JSON type, e.g. a string for totalIncome, that field is named.
+func bindError(ctx context.Context, err error) *echo.HTTPError {
+	printer := i18n.Printer(ctx)
+	message := printer.Sprintf("Invalid input data")
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidRequest, message, schemas.FieldError{
+			Field:   typeErr.Field,
+			Code:    schemas.FieldCodeInvalidType,
+			Message: printer.Sprintf("%s must be a %s", typeErr.Field, printer.Sprintf(jsonTypeName(typeErr.Type))),
+		})
+	}
+	return apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidRequest, message)
+}
+
+func jsonTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "string"
+	}
+}
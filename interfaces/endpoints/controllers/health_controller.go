@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+)
+
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+
+	readinessCheckTimeout = 2 * time.Second
+)
+
+// ReadinessCheck is a dependency that has to be usable before the service can
+// take traffic.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthController struct {
+	readinessChecks []ReadinessCheck
+	checkTimeout    time.Duration
+}
+
+func NewHealthController(readinessChecks ...ReadinessCheck) *HealthController {
+	return &HealthController{
+		readinessChecks: readinessChecks,
+		checkTimeout:    readinessCheckTimeout,
+	}
+}
+
+// Liveness reports that the process is running
+// @Summary Liveness probe
+// @Description Always succeeds while the server can handle requests. It does not check dependencies, so a database outage does not get the process restarted.
+// @Tags health
+// @Produce json
+// @Success 200 {object} schemas.HealthResponse
+// @Router /healthz [get]
+func (hc *HealthController) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, schemas.HealthResponse{Status: healthStatusOK})
+}
+
+// Readiness reports whether the dependencies needed to serve requests work
+// @Summary Readiness probe
+// @Description Runs every readiness check, such as database connectivity, migration status and the presence of the deduction configuration, and reports the status and latency of each.
+// @Tags health
+// @Produce json
+// @Success 200 {object} schemas.HealthResponse
+// @Failure 503 {object} schemas.HealthResponse "At least one check failed"
+// @Router /readyz [get]
+func (hc *HealthController) Readiness(c echo.Context) error {
+	results := make([]schemas.HealthCheckResult, len(hc.readinessChecks))
+
+	var wg sync.WaitGroup
+	for i, check := range hc.readinessChecks {
+		wg.Add(1)
+		go func(i int, check ReadinessCheck) {
+			defer wg.Done()
+			results[i] = hc.runReadinessCheck(c.Request().Context(), check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	response := schemas.HealthResponse{Status: healthStatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status != healthStatusOK {
+			response.Status = healthStatusFail
+			return c.JSON(http.StatusServiceUnavailable, response)
+		}
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (hc *HealthController) runReadinessCheck(ctx context.Context, check ReadinessCheck) schemas.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, hc.checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := schemas.HealthCheckResult{
+		Name:      check.Name,
+		Status:    healthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = healthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+)
+
+func passingCheck(ctx context.Context) error { return nil }
+
+func TestHealthController_Liveness(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	controller := NewHealthController(ReadinessCheck{Name: "database", Check: func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	if assert.NoError(t, controller.Liveness(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	}
+}
+
+func TestHealthController_Readiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         []ReadinessCheck
+		expectedCode   int
+		expectedStatus string
+		expectedErrors []string
+	}{
+		{
+			name:           "All checks pass",
+			checks:         []ReadinessCheck{{"database", passingCheck}, {"config", passingCheck}},
+			expectedCode:   http.StatusOK,
+			expectedStatus: "ok",
+			expectedErrors: []string{"", ""},
+		},
+		{
+			name: "One check fails",
+			checks: []ReadinessCheck{{"database", passingCheck}, {"migrations", func(ctx context.Context) error {
+				return errors.New("database schema is at version 2 but version 4 is required")
+			}}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "fail",
+			expectedErrors: []string{"", "database schema is at version 2 but version 4 is required"},
+		},
+		{
+			name: "Check exceeds its deadline",
+			checks: []ReadinessCheck{{"database", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "fail",
+			expectedErrors: []string{context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			controller := NewHealthController(tt.checks...)
+			controller.checkTimeout = 20 * time.Millisecond
+
+			if assert.NoError(t, controller.Readiness(c)) {
+				assert.Equal(t, tt.expectedCode, rec.Code)
+
+				var resp schemas.HealthResponse
+				if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+					assert.Equal(t, tt.expectedStatus, resp.Status)
+					if assert.Len(t, resp.Checks, len(tt.checks)) {
+						for i, check := range resp.Checks {
+							assert.Equal(t, tt.checks[i].Name, check.Name)
+							assert.Equal(t, tt.expectedErrors[i], check.Error)
+							assert.GreaterOrEqual(t, check.LatencyMs, 0.0)
+						}
+					}
+				}
+			}
+		})
+	}
+}
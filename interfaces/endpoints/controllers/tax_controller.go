@@ -1,21 +1,32 @@
 package controllers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"strconv"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/thitiphum-bluesage/assessment-tax/applications/services/tax"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/tracing"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
 	"github.com/thitiphum-bluesage/assessment-tax/utilities"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TaxController struct {
-	taxService tax.TaxServiceInterface
+	taxService       tax.TaxServiceInterface
+	csvHeaderAliases map[string]string
 }
 
 func NewTaxController(service tax.TaxServiceInterface) *TaxController {
@@ -24,20 +35,25 @@ func NewTaxController(service tax.TaxServiceInterface) *TaxController {
 	}
 }
 
+// SetCSVHeaderAliases registers extra CSV header aliases on top of the built-in
+// ones. Keys are lower-case headers and values are canonical column names.
+func (tc *TaxController) SetCSVHeaderAliases(aliases map[string]string) {
+	tc.csvHeaderAliases = aliases
+}
+
 // used in story 1,2,3
-func (tc *TaxController) CalculateTax(c echo.Context) error {
-	var req schemas.TaxCalculationRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid input data")
-	}
+func (tc *TaxController) CalculateTax(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateTax")
+	defer func() { tracing.End(span, err) }()
 
-	if err := utilities.ValidateTaxCalculationRequest(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	var req schemas.TaxCalculationRequest
+	if err := bindTaxCalculationRequest(ctx, c, &req); err != nil {
+		return err
 	}
 
-	netTax, taxRefund, err := tc.taxService.CalculateTax(*req.TotalIncome, *req.WHT, req.Allowances)
+	netTax, taxRefund, err := tc.taxService.CalculateTax(ctx, *req.TotalIncome, *req.WHT, req.Allowances)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return internalError(err)
 	}
 
 	if taxRefund > 0 {
@@ -53,6 +69,23 @@ func (tc *TaxController) CalculateTax(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// bindTaxCalculationRequest binds and validates the JSON body in its own span,
+// so that slow or oversized request bodies show up separately from the
+// calculation.
+func bindTaxCalculationRequest(ctx context.Context, c echo.Context, req *schemas.TaxCalculationRequest) (err error) {
+	_, span := tracing.Start(ctx, "bind request")
+	defer func() { tracing.End(span, err) }()
+
+	if err := c.Bind(req); err != nil {
+		return bindError(ctx, err)
+	}
+
+	if err := utilities.ValidateTaxCalculationRequest(req); err != nil {
+		return validationError(ctx, err)
+	}
+	return nil
+}
+
 // CalculateDetailedTax calculates the detailed tax amounts based on income, withholdings, and allowances
 // @Summary Calculate detailed tax
 // @Description Calculates taxes including breakdowns by tax level and potential refunds.
@@ -60,29 +93,31 @@ func (tc *TaxController) CalculateTax(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param request body schemas.TaxCalculationRequest true "Tax Calculation Request"
+// @Param Accept-Language header string false "th or en; language of tax level labels and error messages"
 // @Success 200 {object} schemas.DetailedTaxCalculationResponse "Detailed breakdown of tax calculations"
 // @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
 // @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Deprecated
 // @Router /tax/calculations [post]
-func (tc *TaxController) CalculateDetailedTax(c echo.Context) error {
-	var req schemas.TaxCalculationRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid input data")
-	}
+// @Router /v1/tax/calculations [post]
+func (tc *TaxController) CalculateDetailedTax(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateDetailedTax")
+	defer func() { tracing.End(span, err) }()
 
-	if err := utilities.ValidateTaxCalculationRequest(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	var req schemas.TaxCalculationRequest
+	if err := bindTaxCalculationRequest(ctx, c, &req); err != nil {
+		return err
 	}
 
-	taxLevel, netTax, taxRefund, err := tc.taxService.CalculateDetailedTax(*req.TotalIncome, *req.WHT, req.Allowances)
+	taxLevel, netTax, taxRefund, err := tc.taxService.CalculateDetailedTax(ctx, *req.TotalIncome, *req.WHT, req.Allowances)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return internalError(err)
 	}
 
 	if taxRefund > 0 {
 		response := schemas.TaxCalculationRefundResponse{
 			TaxRefund: taxRefund,
-            TaxLevel: taxLevel,
+			TaxLevel:  taxLevel,
 		}
 		return c.JSON(http.StatusOK, response)
 	}
@@ -94,109 +129,558 @@ func (tc *TaxController) CalculateDetailedTax(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// CalculateTaxV2 calculates tax with the full breakdown of deductions and brackets
+// @Summary Calculate tax (v2)
+// @Description Calculates tax and always returns the tax, refund, taxable income, total deductions, gross tax, WHT credited and net tax, every deduction line with the amount claimed and allowed, the cap applied and why, and every bracket with its bounds and rate.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body schemas.TaxCalculationRequest true "Tax Calculation Request"
+// @Param Accept-Language header string false "th or en; language of tax level labels, deduction explanations and error messages"
+// @Success 200 {object} schemas.TaxCalculationV2Response "Tax calculation with deductions and brackets"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/calculations [post]
+func (tc *TaxController) CalculateTaxV2(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateTaxV2")
+	defer func() { tracing.End(span, err) }()
+
+	var req schemas.TaxCalculationRequest
+	if err := bindTaxCalculationRequest(ctx, c, &req); err != nil {
+		return err
+	}
+
+	response, err := tc.taxService.CalculateTaxBreakdown(ctx, *req.TotalIncome, *req.WHT, req.Allowances)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GrossUp finds the gross income that leaves a target net income after tax
+// @Summary Gross up a net income
+// @Description Solves for the gross income whose take-home pay after personal income tax equals targetNetIncome, given the allowances, and returns it with the tax and bracket breakdown. whtPolicy decides how the tax is settled: full withholds all of it (default), none withholds nothing, and rate withholds whtRate of the gross income.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body schemas.GrossUpRequest true "Gross-up Request"
+// @Param Accept-Language header string false "th or en; language of tax level labels, deduction explanations and error messages"
+// @Success 200 {object} schemas.GrossUpResponse "Gross income with its tax calculation"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/gross-up [post]
+func (tc *TaxController) GrossUp(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.GrossUp")
+	defer func() { tracing.End(span, err) }()
+
+	var req schemas.GrossUpRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(ctx, err)
+	}
+	if req.WHTPolicy == "" {
+		req.WHTPolicy = schemas.WHTPolicyFull
+	}
+	if err := utilities.ValidateGrossUpRequest(&req); err != nil {
+		return validationError(ctx, err)
+	}
+
+	whtRate := 0.0
+	if req.WHTRate != nil {
+		whtRate = *req.WHTRate
+	}
+	response, err := tc.taxService.GrossUp(ctx, *req.TargetNetIncome, req.Allowances, req.WHTPolicy, whtRate)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// CompareScenarios calculates a base request and named variants side by side
+// @Summary Compare tax scenarios
+// @Description Calculates a base tax calculation request and up to 20 named variants with the same deduction configuration, and returns each v2 calculation with its difference from the base: tax, refund, taxable income, deductions and the tax of every bracket.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body schemas.ScenarioComparisonRequest true "Base request and named variants"
+// @Param Accept-Language header string false "th or en; language of tax level labels, deduction explanations and error messages"
+// @Success 200 {object} schemas.ScenarioComparisonResponse "Calculations with their differences from the base"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/scenarios/compare [post]
+func (tc *TaxController) CompareScenarios(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CompareScenarios")
+	defer func() { tracing.End(span, err) }()
+
+	var req schemas.ScenarioComparisonRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(ctx, err)
+	}
+	if err := utilities.ValidateScenarioComparisonRequest(&req); err != nil {
+		return validationError(ctx, err)
+	}
+
+	response, err := tc.taxService.CompareScenarios(ctx, *req.Base, req.Variants)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// OptimizeDeductions suggests how to spend a budget on deductible items
+// @Summary Optimize deductions
+// @Description Allocates a budget to the room left under the configured donation and k-receipt caps, on top of the allowances already claimed, to give the lowest tax. items limits and orders the allowance types the budget goes to; insurance, rmf and ssf have no cap yet and are rejected with not_optimizable. Returns the allocation, the tax saved per baht spent, the saturation point past which spending no longer reduces tax, and the v2 calculation with the allocation applied.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body schemas.DeductionOptimizationRequest true "Tax calculation request with a budget"
+// @Param Accept-Language header string false "th or en; language of tax level labels, deduction explanations and error messages"
+// @Success 200 {object} schemas.DeductionOptimizationResponse "Allocation with the lowest tax"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/deductions/optimize [post]
+func (tc *TaxController) OptimizeDeductions(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.OptimizeDeductions")
+	defer func() { tracing.End(span, err) }()
+
+	var req schemas.DeductionOptimizationRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(ctx, err)
+	}
+	if err := utilities.ValidateDeductionOptimizationRequest(&req); err != nil {
+		return validationError(ctx, err)
+	}
+
+	response, err := tc.taxService.OptimizeDeductions(ctx, *req.TotalIncome, *req.WHT, req.Allowances, *req.Budget, req.Items)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// CalculateWithholding works out monthly PND1 withholding for a year of pay
+// @Summary Calculate monthly withholding (PND1)
+// @Description Returns a 12-month withholding schedule. Each month annualizes the pay to date with the month's salary projected over the remaining months, and spreads the tax not yet withheld over the remaining pay periods. The extra tax caused by a bonus is withheld in the month it is paid. The same allowances and brackets as the annual calculation are used, so the year's withholding adds up to the annual tax.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body schemas.WithholdingRequest true "Monthly salary, payments by month and allowances"
+// @Param Accept-Language header string false "th or en; language of tax level labels, deduction explanations and error messages"
+// @Success 200 {object} schemas.WithholdingResponse "12-month withholding schedule"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/withholding [post]
+func (tc *TaxController) CalculateWithholding(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateWithholding")
+	defer func() { tracing.End(span, err) }()
+
+	var req schemas.WithholdingRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(ctx, err)
+	}
+	if err := utilities.ValidateWithholdingRequest(&req); err != nil {
+		return validationError(ctx, err)
+	}
+
+	response, err := tc.taxService.CalculateWithholding(ctx, *req.MonthlySalary, req.Payments, req.Allowances)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// ProjectTax projects the tax of the next five years
+// @Summary Project tax over five years
+// @Description Calculates five tax years from startYear, which defaults to the current year. Income starts at startingIncome and grows by growthRate a year, and whtRate of each year's income is withheld. The plan lists allowances by year; a year without an entry keeps the allowances of the year before. Each year is calculated with the deduction configuration for its tax year when one exists and with the latest configuration (MainConfig) otherwise. Returns a yearly table with cumulative tax and refund, and the totals.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param request body schemas.ProjectionRequest true "Start year, starting income, growth rate, WHT rate and allowance plan"
+// @Param Accept-Language header string false "th or en; language of tax level labels, deduction explanations and error messages"
+// @Success 200 {object} schemas.ProjectionResponse "Yearly and cumulative tax and refund"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/projections [post]
+func (tc *TaxController) ProjectTax(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.ProjectTax")
+	defer func() { tracing.End(span, err) }()
+
+	var req schemas.ProjectionRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(ctx, err)
+	}
+	if req.StartYear == nil {
+		currentYear := time.Now().Year()
+		req.StartYear = &currentYear
+	}
+	if err := utilities.ValidateProjectionRequest(&req); err != nil {
+		return validationError(ctx, err)
+	}
+
+	response, err := tc.taxService.ProjectTax(ctx, *req.StartYear, *req.StartingIncome, req.GrowthRate, req.WHTRate, req.Plan)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
 // CalculateCSVTax calculates taxes from a CSV file upload containing multiple taxpayer records.
 // @Summary Calculate taxes from CSV
-// @Description Accepts a file upload (CSV format) with tax data, processes each record, and returns tax calculations.
+// @Description Accepts a file upload (CSV format) with tax data, processes each record, and returns tax calculations. Comma, semicolon and tab delimited files in UTF-8 (with or without BOM) or TIS-620 are accepted, headers may use English or Thai aliases, and numbers may contain thousands separators.
 // @Tags tax
 // @Accept multipart/form-data
 // @Produce json
 // @Param taxFile formData file true "CSV file containing tax data"
+// @Param Idempotency-Key header string false "Key that makes retries return the original response"
 // @Success 200 {object} schemas.CSVResponse "Tax calculations for all records in the uploaded CSV"
 // @Failure 400 {object} schemas.ErrorResponse "Invalid input data or CSV format errors"
+// @Failure 409 {object} schemas.ErrorResponse "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} schemas.ErrorResponse "Idempotency-Key reused with a different file"
 // @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
 // @Router /tax/calculations/upload-csv [post]
-func (tc *TaxController) CalculateCSVTax(c echo.Context) error {
-    fileHeader, err := c.FormFile("taxFile")
-    if err != nil {
-        return echo.NewHTTPError(http.StatusBadRequest, "Failed to get the file")
-    }
-
-    file, err := fileHeader.Open()
-    if err != nil {
-        return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open the file")
-    }
-    defer file.Close()
-
-    csvReader := csv.NewReader(file)
-    headers, err := csvReader.Read()
-    if err != nil {
-        return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read headers from CSV file")
-    }
-
-    columnIndex := make(map[string]int)
-    for i, header := range headers {
-        columnIndex[strings.ToLower(header)] = i
-    }
-
-    hasTotalIncome := false
-    for i, header := range headers {
-        normalizedHeader := strings.ToLower(header)
-        columnIndex[normalizedHeader] = i
-        if normalizedHeader == "totalincome" {
-            hasTotalIncome = true
-        }
-    }
-    if !hasTotalIncome {
-        return echo.NewHTTPError(http.StatusBadRequest, "CSV file does not contain 'totalincome' header")
-    }
-
-    // Validate expected columns
-    for key:= range columnIndex {
-        if key != "totalincome" && key != "wht" && key != "donation" && key != "k-receipt" {
-            return echo.NewHTTPError(http.StatusBadRequest, "Invalid CSV file")
-        }
-    }
-
-    var taxRecords []schemas.CSVObjectFormat
-    for {
-        record, err := csvReader.Read()
-        if err == io.EOF {
-            break
-        }
-        if err != nil {
-            return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read record from CSV file")
-        }
-
-        var taxRecord schemas.CSVObjectFormat
-        for key, index := range columnIndex {
-            switch key {
-            case "totalincome":
-                if taxRecord.TotalIncome, err = parseAndValidateFloat(record[index]); err != nil {
-                    return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid CSV file: %v", err))
-                }
-            case "wht":
-                if taxRecord.WHT, err = parseAndValidateFloat(record[index]); err != nil {
-                    return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid CSV file: %v", err))
-                }
-            case "donation":
-                if taxRecord.Donation, err = parseAndValidateFloat(record[index]); err != nil {
-                    return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid CSV file: %v", err))
-                }
-            case "k-receipt":
-                if taxRecord.KReceipt, err = parseAndValidateFloat(record[index]); err != nil {
-                    return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid CSV file: %v", err))
-                }
-            }
-        }
-
-        taxRecords = append(taxRecords, taxRecord)
-    }
-
-    if err := utilities.ValidateCSVTaxRecords(taxRecords); err != nil {
-        return echo.NewHTTPError(http.StatusBadRequest, err.Error())
-    }
-
-    response, err := tc.taxService.CalculateTaxFromCSV(taxRecords)
-    if err != nil {
-        return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-    }
-    return c.JSON(http.StatusOK, response)
-}
-
-func parseAndValidateFloat(value string) (float64, error) {
-    floatValue, err := strconv.ParseFloat(value, 64)
-    if err != nil {
-        return 0, err
-    }
-    return floatValue, nil
-}
\ No newline at end of file
+// @Router /v1/tax/calculations/upload-csv [post]
+func (tc *TaxController) CalculateCSVTax(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateCSVTax")
+	defer func() { tracing.End(span, err) }()
+
+	taxRecords, err := tc.parseTaxCSV(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	result, err := tc.taxService.CalculateTaxFromCSV(ctx, taxRecords)
+	if err != nil {
+		return internalError(err)
+	}
+
+	response := schemas.CSVResponse{Taxes: make([]schemas.CSVResponseMember, len(result.Taxes))}
+	for i, tax := range result.Taxes {
+		response.Taxes[i] = tax.CSVResponseMember
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// CalculateCSVTaxV2 calculates taxes from a CSV file upload and adds the tax
+// rates of every record.
+// @Summary Calculate taxes from CSV
+// @Description Same as the v1 CSV upload, and every record also carries its marginal rate, effective rate and distance to the next bracket.
+// @Tags tax
+// @Accept multipart/form-data
+// @Produce json
+// @Param taxFile formData file true "CSV file containing tax data"
+// @Param Idempotency-Key header string false "Key that makes retries return the original response"
+// @Success 200 {object} schemas.CSVResponseV2 "Tax calculations for all records in the uploaded CSV"
+// @Failure 400 {object} schemas.ErrorResponse "Invalid input data or CSV format errors"
+// @Failure 409 {object} schemas.ErrorResponse "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} schemas.ErrorResponse "Idempotency-Key reused with a different file"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /v2/tax/calculations/upload-csv [post]
+func (tc *TaxController) CalculateCSVTaxV2(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateCSVTaxV2")
+	defer func() { tracing.End(span, err) }()
+
+	taxRecords, err := tc.parseTaxCSV(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	response, err := tc.taxService.CalculateTaxFromCSV(ctx, taxRecords)
+	if err != nil {
+		return internalError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// parseTaxCSV reads and validates every record of the uploaded file, stopping
+// at the first problem.
+func (tc *TaxController) parseTaxCSV(ctx context.Context, c echo.Context) (taxRecords []schemas.CSVObjectFormat, err error) {
+	ctx, span := tracing.Start(ctx, "parse CSV")
+	defer func() {
+		span.SetAttributes(attribute.Int("csv.rows", len(taxRecords)))
+		tracing.End(span, err)
+	}()
+
+	csvReader, err := openTaxCSVFile(c)
+	if err != nil {
+		return nil, err
+	}
+
+	printer := i18n.Printer(ctx)
+	headers, err := csvReader.Read()
+	if err != nil {
+		return nil, apiError(http.StatusInternalServerError, schemas.ErrorCodeInternal, printer.Sprintf("Failed to read headers from CSV file"))
+	}
+
+	columns, headerErrs := tc.resolveCSVHeaders(headers)
+	if len(headerErrs) > 0 {
+		fields := make([]schemas.FieldError, len(headerErrs))
+		for i, headerErr := range headerErrs {
+			fields[i] = schemas.FieldError{Field: headerErr.Column, Code: headerErr.Code, Message: printer.Sprintf(headerErr.Message)}
+		}
+		return nil, apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidCSV,
+			printer.Sprintf("Invalid CSV file: %s", headerErrs[0].localize(printer)), fields...)
+	}
+
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, apiError(http.StatusInternalServerError, schemas.ErrorCodeInternal, printer.Sprintf("Failed to read record from CSV file"))
+		}
+
+		taxRecord, fieldErrs := parseCSVRecord(record, columns, row)
+		if len(fieldErrs) > 0 {
+			// The error message quotes the offending value, which may be an
+			// income, so only its position is logged.
+			slog.InfoContext(ctx, "Rejected CSV upload", "row", fieldErrs[0].Row, "column", fieldErrs[0].Column)
+			metrics.CSVRowsRejected.Inc()
+			fields := make([]schemas.FieldError, len(fieldErrs))
+			for i, fieldErr := range fieldErrs {
+				fields[i] = schemas.FieldError{
+					Field: fieldErr.Column, Row: fieldErr.Row, Code: schemas.FieldCodeInvalidNumber, Value: fieldErr.Value, Message: printer.Sprintf("invalid number"),
+				}
+			}
+			return nil, apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidCSV,
+				printer.Sprintf("Invalid CSV file: %s", fieldErrs[0].localize(printer)), fields...)
+		}
+
+		taxRecords = append(taxRecords, taxRecord)
+	}
+
+	if err := utilities.ValidateCSVTaxRecords(taxRecords); err != nil {
+		slog.InfoContext(ctx, "Rejected CSV upload", "rows", len(taxRecords), "error", err)
+		issues := utilities.CollectCSVTaxRecordIssues(taxRecords)
+		metrics.CSVRowsRejected.Add(float64(countRowsWithIssues(issues)))
+		fields := make([]schemas.FieldError, len(issues))
+		records := make([]string, len(issues))
+		for i, issue := range issues {
+			message := printer.Sprintf(issue.Message, issue.Args...)
+			fields[i] = schemas.FieldError{Field: issue.Column, Row: issue.Row, Code: issue.Code, Message: message}
+			records[i] = printer.Sprintf("Record %d: %s", issue.Row, message)
+		}
+		return nil, apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidCSV,
+			printer.Sprintf("validation errors: %s", strings.Join(records, "; ")), fields...)
+	}
+	return taxRecords, nil
+}
+
+func countRowsWithIssues(issues []utilities.CSVRecordIssue) int {
+	rows := map[int]bool{}
+	for _, issue := range issues {
+		rows[issue.Row] = true
+	}
+	return len(rows)
+}
+
+// ValidateCSVTax checks an uploaded CSV file without calculating any taxes.
+// @Summary Validate a tax CSV file
+// @Description Dry run for the CSV upload: checks headers, number formats and record values, and reports every problem with its row and column. No taxes are calculated and nothing is stored. Row 0 refers to the header line.
+// @Tags tax
+// @Accept multipart/form-data
+// @Produce json
+// @Param taxFile formData file true "CSV file containing tax data"
+// @Success 200 {object} schemas.CSVValidationReport "Validation report for the uploaded CSV"
+// @Failure 400 {object} schemas.ErrorResponse "File missing or not readable"
+// @Failure 500 {object} schemas.ErrorResponse "Internal Server Error"
+// @Router /tax/calculations/upload-csv/validate [post]
+// @Router /v1/tax/calculations/upload-csv/validate [post]
+// @Router /v2/tax/calculations/upload-csv/validate [post]
+func (tc *TaxController) ValidateCSVTax(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.ValidateCSVTax")
+	defer func() { tracing.End(span, err) }()
+
+	csvReader, err := openTaxCSVFile(c)
+	if err != nil {
+		return err
+	}
+
+	report := schemas.CSVValidationReport{Problems: []schemas.CSVValidationProblem{}}
+	addProblem := func(row int, column, value, message string, args ...interface{}) {
+		if len(args) > 0 {
+			message = i18n.Printer(ctx).Sprintf(message, args...)
+		} else {
+			message = i18n.Translate(ctx, message)
+		}
+		report.Problems = append(report.Problems, schemas.CSVValidationProblem{
+			Row: row, Column: column, Value: value, Message: message,
+		})
+	}
+
+	headers, err := csvReader.Read()
+	if err != nil {
+		addProblem(0, "", "", "Failed to read headers from CSV file")
+		return c.JSON(http.StatusOK, report)
+	}
+
+	columns, headerErrs := tc.resolveCSVHeaders(headers)
+	report.Columns = columns
+	for _, headerErr := range headerErrs {
+		addProblem(0, headerErr.Column, "", headerErr.Message)
+	}
+
+	// rows maps the position of each parsed record to its data row number, so
+	// record issues can be reported against the right row even when other rows
+	// could not be parsed.
+	var taxRecords []schemas.CSVObjectFormat
+	var rows []int
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		report.RowCount++
+		if err != nil {
+			addProblem(row, "", "", err.Error())
+			if _, ok := err.(*csv.ParseError); ok {
+				continue
+			}
+			break
+		}
+		if len(headerErrs) > 0 {
+			continue
+		}
+
+		taxRecord, fieldErrs := parseCSVRecord(record, columns, row)
+		for _, fieldErr := range fieldErrs {
+			addProblem(fieldErr.Row, fieldErr.Column, fieldErr.Value, "invalid number")
+		}
+		if len(fieldErrs) == 0 {
+			taxRecords = append(taxRecords, taxRecord)
+			rows = append(rows, row)
+		}
+	}
+
+	for _, issue := range utilities.CollectCSVTaxRecordIssues(taxRecords) {
+		addProblem(rows[issue.Row-1], issue.Column, "", issue.Message, issue.Args...)
+	}
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		return report.Problems[i].Row < report.Problems[j].Row
+	})
+
+	report.Valid = len(report.Problems) == 0
+	return c.JSON(http.StatusOK, report)
+}
+
+// openTaxCSVFile reads the "taxFile" upload of the request and returns a
+// csv.Reader for it.
+func openTaxCSVFile(c echo.Context) (*csv.Reader, error) {
+	fileHeader, err := c.FormFile("taxFile")
+	if err != nil {
+		return nil, apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidRequest, i18n.Translate(c.Request().Context(), "Failed to get the file"))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, apiError(http.StatusInternalServerError, schemas.ErrorCodeInternal, i18n.Translate(c.Request().Context(), "Failed to open the file"))
+	}
+	defer file.Close()
+
+	csvReader, err := newTaxCSVReader(file)
+	if err != nil {
+		return nil, apiError(http.StatusBadRequest, schemas.ErrorCodeInvalidCSV, i18n.Translate(c.Request().Context(), "Failed to decode the CSV file"))
+	}
+	return csvReader, nil
+}
+
+// maxBatchLineSize caps the size of a single NDJSON line.
+const maxBatchLineSize = 1024 * 1024
+
+// CalculateBatchTax calculates taxes for a stream of newline-delimited JSON requests.
+// @Summary Calculate taxes from NDJSON
+// @Description Accepts one tax calculation request per line (NDJSON / JSON Lines) and streams back one detailed result per line in the same order. Lines that cannot be parsed, validated or calculated produce a result with an error message instead of failing the whole batch. A stream canceled part way ends with a line whose errorCode is canceled.
+// @Tags tax
+// @Accept application/x-ndjson
+// @Produce application/x-ndjson
+// @Param request body schemas.TaxCalculationRequest true "One Tax Calculation Request per line"
+// @Param Idempotency-Key header string false "Key that makes retries return the original response"
+// @Success 200 {object} schemas.BatchTaxCalculationResult "One result per input line"
+// @Failure 409 {object} schemas.ErrorResponse "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} schemas.ErrorResponse "Idempotency-Key reused with a different payload"
+// @Router /tax/calculations/batch [post]
+// @Router /v1/tax/calculations/batch [post]
+// @Router /v2/tax/calculations/batch [post]
+func (tc *TaxController) CalculateBatchTax(c echo.Context) (err error) {
+	ctx, span := tracing.Start(c.Request().Context(), "TaxController.CalculateBatchTax")
+	defer func() { tracing.End(span, err) }()
+
+	scanner := bufio.NewScanner(c.Request().Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineSize)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+
+	line := 0
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			// The status is already sent, so the stream has to say itself that
+			// it was cut short.
+			encoder.Encode(schemas.BatchTaxCalculationResult{
+				Line:      line + 1,
+				Error:     i18n.Translate(ctx, "Request canceled before every line was processed"),
+				ErrorCode: schemas.ErrorCodeCanceled,
+			})
+			res.Flush()
+			return err
+		}
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		result := tc.calculateBatchLine(ctx, line, scanner.Bytes())
+		if result.Error != "" {
+			metrics.BatchLines.WithLabelValues(metrics.ResultError).Inc()
+		} else {
+			metrics.BatchLines.WithLabelValues(metrics.ResultSuccess).Inc()
+		}
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		res.Flush()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return encoder.Encode(schemas.BatchTaxCalculationResult{
+			Line:      line + 1,
+			Error:     fmt.Sprintf("Failed to read line: %v", err),
+			ErrorCode: schemas.ErrorCodeInvalidRequest,
+		})
+	}
+	return nil
+}
+
+func (tc *TaxController) calculateBatchLine(ctx context.Context, line int, data []byte) schemas.BatchTaxCalculationResult {
+	result := schemas.BatchTaxCalculationResult{Line: line}
+
+	var req schemas.TaxCalculationRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return withLineError(result, bindError(ctx, err))
+	}
+
+	if err := utilities.ValidateTaxCalculationRequest(&req); err != nil {
+		return withLineError(result, validationError(ctx, err))
+	}
+
+	taxLevel, netTax, taxRefund, err := tc.taxService.CalculateDetailedTax(ctx, *req.TotalIncome, *req.WHT, req.Allowances)
+	if err != nil {
+		return withLineError(result, internalError(err))
+	}
+
+	result.Tax = netTax
+	result.TaxRefund = taxRefund
+	result.TaxLevel = taxLevel
+	return result
+}
+
+// withLineError copies the envelope of httpErr into a batch result, since a
+// failed line is reported in the stream rather than as an HTTP error.
+func withLineError(result schemas.BatchTaxCalculationResult, httpErr *echo.HTTPError) schemas.BatchTaxCalculationResult {
+	response := httpErr.Message.(schemas.ErrorResponse)
+	result.Error = response.Message
+	result.ErrorCode = response.Code
+	result.Errors = response.Errors
+	return result
+}
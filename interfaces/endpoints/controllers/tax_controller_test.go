@@ -2,17 +2,28 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/language"
 )
 
 type MockTaxService struct {
@@ -20,21 +31,57 @@ type MockTaxService struct {
 }
 
 // Mock implementation of CalculateTax
-func (m *MockTaxService) CalculateTax(totalIncome float64, wht float64, allowances []schemas.Allowance) (float64, float64, error) {
+func (m *MockTaxService) CalculateTax(ctx context.Context, totalIncome float64, wht float64, allowances []schemas.Allowance) (float64, float64, error) {
 	args := m.Called(totalIncome, wht, allowances)
 	return args.Get(0).(float64), args.Get(1).(float64), args.Error(2)
 }
 
 // Mock implementation of CalculateDetailedTax
-func (m *MockTaxService) CalculateDetailedTax(totalIncome, wht float64, allowances []schemas.Allowance) ([]schemas.TaxLevel, float64, float64, error) {
+func (m *MockTaxService) CalculateDetailedTax(ctx context.Context, totalIncome, wht float64, allowances []schemas.Allowance) ([]schemas.TaxLevel, float64, float64, error) {
 	args := m.Called(totalIncome, wht, allowances)
 	return args.Get(0).([]schemas.TaxLevel), args.Get(1).(float64), args.Get(2).(float64), args.Error(3)
 }
 
+// Mock implementation of CalculateTaxBreakdown
+func (m *MockTaxService) CalculateTaxBreakdown(ctx context.Context, totalIncome, wht float64, allowances []schemas.Allowance) (schemas.TaxCalculationV2Response, error) {
+	args := m.Called(totalIncome, wht, allowances)
+	return args.Get(0).(schemas.TaxCalculationV2Response), args.Error(1)
+}
+
+// Mock implementation of GrossUp
+func (m *MockTaxService) GrossUp(ctx context.Context, targetNetIncome float64, allowances []schemas.Allowance, whtPolicy string, whtRate float64) (schemas.GrossUpResponse, error) {
+	args := m.Called(targetNetIncome, allowances, whtPolicy, whtRate)
+	return args.Get(0).(schemas.GrossUpResponse), args.Error(1)
+}
+
+// Mock implementation of CompareScenarios
+func (m *MockTaxService) CompareScenarios(ctx context.Context, base schemas.TaxCalculationRequest, variants []schemas.ScenarioVariant) (schemas.ScenarioComparisonResponse, error) {
+	args := m.Called(base, variants)
+	return args.Get(0).(schemas.ScenarioComparisonResponse), args.Error(1)
+}
+
+// Mock implementation of OptimizeDeductions
+func (m *MockTaxService) OptimizeDeductions(ctx context.Context, totalIncome, wht float64, allowances []schemas.Allowance, budget float64, items []string) (schemas.DeductionOptimizationResponse, error) {
+	args := m.Called(totalIncome, wht, allowances, budget, items)
+	return args.Get(0).(schemas.DeductionOptimizationResponse), args.Error(1)
+}
+
+// Mock implementation of CalculateWithholding
+func (m *MockTaxService) CalculateWithholding(ctx context.Context, monthlySalary float64, payments []schemas.MonthlyPayment, allowances []schemas.Allowance) (schemas.WithholdingResponse, error) {
+	args := m.Called(monthlySalary, payments, allowances)
+	return args.Get(0).(schemas.WithholdingResponse), args.Error(1)
+}
+
+// Mock implementation of ProjectTax
+func (m *MockTaxService) ProjectTax(ctx context.Context, startYear int, startingIncome, growthRate, whtRate float64, plan []schemas.YearAllowances) (schemas.ProjectionResponse, error) {
+	args := m.Called(startYear, startingIncome, growthRate, whtRate, plan)
+	return args.Get(0).(schemas.ProjectionResponse), args.Error(1)
+}
+
 // Mock implementation of CalculateTaxFromCSV
-func (m *MockTaxService) CalculateTaxFromCSV(records []schemas.CSVObjectFormat) (schemas.CSVResponse, error) {
+func (m *MockTaxService) CalculateTaxFromCSV(ctx context.Context, records []schemas.CSVObjectFormat) (schemas.CSVResponseV2, error) {
 	args := m.Called(records)
-	return args.Get(0).(schemas.CSVResponse), args.Error(1)
+	return args.Get(0).(schemas.CSVResponseV2), args.Error(1)
 }
 
 func TestTaxController_CalculateDetailedTax_Success(t *testing.T) {
@@ -175,18 +222,11 @@ func TestTaxController_CalculateCSVTax_ValidFile(t *testing.T) {
 
     // Set up the mock expectations
     expectedTaxRecords := []schemas.CSVObjectFormat{
-        {TotalIncome: 500000, WHT: 0, Donation: 0},
-        {TotalIncome: 600000, WHT: 40000, Donation: 20000},
-        {TotalIncome: 750000, WHT: 50000, Donation: 15000},
+        {TotalIncome: 500000, WHT: 0, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 0}}},
+        {TotalIncome: 600000, WHT: 40000, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 20000}}},
+        {TotalIncome: 750000, WHT: 50000, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 15000}}},
     }
-    expectedResponse := schemas.CSVResponse{
-        Taxes: []schemas.CSVResponseMember{
-            {TotalIncome: 500000, Tax: 29000},
-            {TotalIncome: 600000, TaxRefund: 2000},
-            {TotalIncome: 750000, Tax: 11250},
-        },
-    }
-    mockTaxService.On("CalculateTaxFromCSV", expectedTaxRecords).Return(expectedResponse, nil)
+    mockTaxService.On("CalculateTaxFromCSV", expectedTaxRecords).Return(csvResponseWithRates, nil)
 
     taxController := &TaxController{
         taxService: mockTaxService,
@@ -194,16 +234,47 @@ func TestTaxController_CalculateCSVTax_ValidFile(t *testing.T) {
 
     if assert.NoError(t, taxController.CalculateCSVTax(e.NewContext(req, rec))) {
         assert.Equal(t, http.StatusOK, rec.Code)
-        // Add assertions for the response body
-        var resp schemas.CSVResponse
-        if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
-            assert.Equal(t, expectedResponse.Taxes, resp.Taxes)
-        }
+        // The v1 body must stay exactly what it was before v2 added tax rates.
+        assert.Equal(t, `{"taxes":[{"totalIncome":500000,"tax":29000},{"totalIncome":600000,"taxRefund":2000},{"totalIncome":750000,"tax":11250}]}`+"\n", rec.Body.String())
     }
 
     mockTaxService.AssertExpectations(t)
 }
 
+func baht(amount float64) *float64 {
+	return &amount
+}
+
+// csvResponseWithRates is what the tax service returns for the rows of
+// TestTaxController_CalculateCSVTax_ValidFile.
+var csvResponseWithRates = schemas.CSVResponseV2{
+	Taxes: []schemas.CSVResponseV2Member{
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 500000, Tax: 29000}, TaxRates: schemas.TaxRates{MarginalRate: 0.1, EffectiveRate: 0.058, DistanceToNextBracket: baht(60000)}},
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 600000, TaxRefund: 2000}, TaxRates: schemas.TaxRates{MarginalRate: 0.15, EffectiveRate: 0.0633, DistanceToNextBracket: baht(480000)}},
+		{CSVResponseMember: schemas.CSVResponseMember{TotalIncome: 750000, Tax: 11250}, TaxRates: schemas.TaxRates{MarginalRate: 0.15, EffectiveRate: 0.0817, DistanceToNextBracket: baht(325000)}},
+	},
+}
+
+func TestTaxController_CalculateCSVTaxV2_IncludesTaxRates(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("totalIncome,wht,donation\n500000,0,0\n600000,40000,20000\n750000,50000,15000\n"))
+	rec := httptest.NewRecorder()
+
+	mockTaxService := new(MockTaxService)
+	mockTaxService.On("CalculateTaxFromCSV", mock.Anything).Return(csvResponseWithRates, nil)
+	taxController := NewTaxController(mockTaxService)
+
+	if assert.NoError(t, taxController.CalculateCSVTaxV2(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp schemas.CSVResponseV2
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, csvResponseWithRates, resp)
+		}
+	}
+	mockTaxService.AssertExpectations(t)
+}
+
 func TestTaxController_CalculateCSVTax_InvalidFile_WHTGreaterThanTotalIncome(t *testing.T) {
     // Create a new Echo instance
     e := echo.New()
@@ -293,3 +364,683 @@ func TestTaxController_CalculateCSVTax_InvalidFile_StringValues(t *testing.T) {
     assert.Error(t, err)
     assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
 }
+
+func newCSVUploadRequest(t *testing.T, csvData []byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("taxFile", "test.csv")
+	assert.NoError(t, err)
+	_, err = part.Write(csvData)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestTaxController_CalculateCSVTax_SemicolonThaiHeadersWithBOM(t *testing.T) {
+	e := echo.New()
+
+	csvData := append([]byte{0xEF, 0xBB, 0xBF}, []byte("รายได้รวม;ภาษีหัก ณ ที่จ่าย;เงินบริจาค;ช้อปลดภาษี\n\"1,200,000.50\";40000;20000;1,000\n")...)
+	req := newCSVUploadRequest(t, csvData)
+	rec := httptest.NewRecorder()
+
+	mockTaxService := new(MockTaxService)
+	expectedTaxRecords := []schemas.CSVObjectFormat{
+		{TotalIncome: 1200000.50, WHT: 40000, Allowances: []schemas.Allowance{
+			{AllowanceType: "donation", Amount: 20000}, {AllowanceType: "k-receipt", Amount: 1000},
+		}},
+	}
+	mockTaxService.On("CalculateTaxFromCSV", expectedTaxRecords).Return(schemas.CSVResponseV2{}, nil)
+
+	taxController := NewTaxController(mockTaxService)
+
+	if assert.NoError(t, taxController.CalculateCSVTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockTaxService.AssertExpectations(t)
+}
+
+func TestTaxController_CalculateCSVTax_AllowanceColumns(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("totalIncome,SSF,insurance,rmf\n1000000,150000,80000,300000\n"))
+	rec := httptest.NewRecorder()
+
+	mockTaxService := new(MockTaxService)
+	expectedTaxRecords := []schemas.CSVObjectFormat{
+		{TotalIncome: 1000000, Allowances: []schemas.Allowance{
+			{AllowanceType: "ssf", Amount: 150000}, {AllowanceType: "insurance", Amount: 80000}, {AllowanceType: "rmf", Amount: 300000},
+		}},
+	}
+	mockTaxService.On("CalculateTaxFromCSV", expectedTaxRecords).Return(schemas.CSVResponseV2{}, nil)
+
+	taxController := NewTaxController(mockTaxService)
+
+	if assert.NoError(t, taxController.CalculateCSVTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockTaxService.AssertExpectations(t)
+}
+
+func TestTaxController_CalculateCSVTax_NegativeAllowanceColumn(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("totalIncome,rmf\n500000,-1\n"))
+	rec := httptest.NewRecorder()
+
+	taxController := NewTaxController(&MockTaxService{})
+
+	err := taxController.CalculateCSVTax(e.NewContext(req, rec))
+	if assert.Error(t, err) {
+		httpErr := err.(*echo.HTTPError)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "rmf", Row: 1, Code: schemas.FieldCodeMustBeNonNegative, Message: "Amount for rmf must be non-negative"},
+		}, httpErr.Message.(schemas.ErrorResponse).Errors)
+	}
+}
+
+func TestTaxController_CalculateCSVTax_TIS620TabDelimitedWithCustomAlias(t *testing.T) {
+	e := echo.New()
+
+	header, err := charmap.Windows874.NewEncoder().String("รายได้รวม\tsalary tax\n")
+	assert.NoError(t, err)
+	req := newCSVUploadRequest(t, []byte(header+"500000\t1000\n"))
+	rec := httptest.NewRecorder()
+
+	mockTaxService := new(MockTaxService)
+	expectedTaxRecords := []schemas.CSVObjectFormat{
+		{TotalIncome: 500000, WHT: 1000},
+	}
+	mockTaxService.On("CalculateTaxFromCSV", expectedTaxRecords).Return(schemas.CSVResponseV2{}, nil)
+
+	taxController := NewTaxController(mockTaxService)
+	taxController.SetCSVHeaderAliases(map[string]string{"salary tax": "wht"})
+
+	if assert.NoError(t, taxController.CalculateCSVTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockTaxService.AssertExpectations(t)
+}
+
+func TestTaxController_CalculateCSVTax_InvalidFile_UnknownColumn(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("totalIncome,bonus\n500000,0\n"))
+	rec := httptest.NewRecorder()
+
+	taxController := NewTaxController(&MockTaxService{})
+
+	err := taxController.CalculateCSVTax(e.NewContext(req, rec))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	assert.Contains(t, err.Error(), "unknown column 'bonus'")
+}
+
+func TestTaxController_CalculateCSVTax_UnreadableRecordInThai(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("totalIncome,wht\n500000,0,1\n"))
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.Thai))
+	rec := httptest.NewRecorder()
+
+	taxController := NewTaxController(&MockTaxService{})
+
+	err := taxController.CalculateCSVTax(e.NewContext(req, rec))
+	httpErr, ok := err.(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusInternalServerError, httpErr.Code)
+		assert.Equal(t, "ไม่สามารถอ่านรายการจากไฟล์ CSV ได้", httpErr.Message.(schemas.ErrorResponse).Message)
+	}
+}
+
+func TestParseAndValidateFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"Plain number", "500000", 500000, false},
+		{"Thousands separators", "1,200,000.50", 1200000.50, false},
+		{"Surrounding spaces", " 1,000 ", 1000, false},
+		{"Misplaced separator", "12,00", 0, true},
+		{"Not a number", "abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAndValidateFloat(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTaxController_CalculateBatchTax(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	allowances := []schemas.Allowance{{AllowanceType: "k-receipt", Amount: 200000}}
+	taxLevels := []schemas.TaxLevel{{Level: "0-150,000", Tax: 0}}
+	mockService.On("CalculateDetailedTax", 500000.0, 0.0, allowances).Return(taxLevels, 29000.0, 0.0, nil)
+	mockService.On("CalculateDetailedTax", 600000.0, 40000.0, []schemas.Allowance(nil)).Return(taxLevels, 0.0, 2000.0, nil)
+
+	reqBody := `{"totalIncome": 500000, "wht": 0, "allowances": [{"allowanceType": "k-receipt", "amount": 200000}]}
+not json
+
+{"totalIncome": 500000, "wht": 600000}
+{"totalIncome": 600000, "wht": 40000}
+`
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.CalculateBatchTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get(echo.HeaderContentType))
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		if assert.Len(t, lines, 4) {
+			results := make([]schemas.BatchTaxCalculationResult, len(lines))
+			for i, line := range lines {
+				assert.NoError(t, json.Unmarshal([]byte(line), &results[i]))
+			}
+			assert.Equal(t, schemas.BatchTaxCalculationResult{Line: 1, Tax: 29000, TaxLevel: taxLevels}, results[0])
+			assert.Equal(t, schemas.BatchTaxCalculationResult{Line: 2, Error: "Invalid input data", ErrorCode: schemas.ErrorCodeInvalidRequest}, results[1])
+			assert.Equal(t, 4, results[2].Line)
+			assert.Contains(t, results[2].Error, "WHT cannot be greater than TotalIncome")
+			assert.Equal(t, schemas.ErrorCodeValidationFailed, results[2].ErrorCode)
+			assert.Equal(t, []schemas.FieldError{{
+				Field: "wht", Code: schemas.FieldCodeExceedsTotalIncome, Value: 600000.0, Message: "WHT cannot be greater than TotalIncome",
+			}}, results[2].Errors)
+			assert.Equal(t, schemas.BatchTaxCalculationResult{Line: 5, TaxRefund: 2000, TaxLevel: taxLevels}, results[3])
+		}
+	}
+	mockService.AssertExpectations(t)
+}
+
+// cancelingReader returns its lines one Read at a time and calls cancel
+// before returning the second.
+type cancelingReader struct {
+	lines  []string
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+	if len(r.lines) == 1 {
+		r.cancel()
+	}
+	n := copy(p, r.lines[0])
+	r.lines = r.lines[1:]
+	return n, nil
+}
+
+func TestTaxController_CalculateBatchTax_CanceledMidStream(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	mockService.On("CalculateDetailedTax", 500000.0, 0.0, []schemas.Allowance(nil)).Return([]schemas.TaxLevel(nil), 29000.0, 0.0, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := &cancelingReader{lines: []string{"{\"totalIncome\": 500000, \"wht\": 0}\n", "{\"totalIncome\": 600000, \"wht\": 0}\n"}, cancel: cancel}
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch", body).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	rec := httptest.NewRecorder()
+
+	err := controller.CalculateBatchTax(e.NewContext(req, rec))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		var last schemas.BatchTaxCalculationResult
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &last))
+		assert.Equal(t, schemas.BatchTaxCalculationResult{
+			Line: 2, Error: "Request canceled before every line was processed", ErrorCode: schemas.ErrorCodeCanceled,
+		}, last)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_ValidateCSVTax_ReportsEveryProblem(t *testing.T) {
+	e := echo.New()
+
+	csvData := "totalIncome,wht,donation\n500000,0,0\nabc,0,x\n400000,500000,-1\n"
+	req := newCSVUploadRequest(t, []byte(csvData))
+	rec := httptest.NewRecorder()
+
+	// The tax service must never be called during validation.
+	taxController := NewTaxController(nil)
+
+	if assert.NoError(t, taxController.ValidateCSVTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var report schemas.CSVValidationReport
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report)) {
+			assert.False(t, report.Valid)
+			assert.Equal(t, []string{"totalincome", "wht", "donation"}, report.Columns)
+			assert.Equal(t, 3, report.RowCount)
+			assert.Equal(t, []schemas.CSVValidationProblem{
+				{Row: 2, Column: "totalincome", Value: "abc", Message: "invalid number"},
+				{Row: 2, Column: "donation", Value: "x", Message: "invalid number"},
+				{Row: 3, Column: "donation", Message: "Donation must be non-negative"},
+				{Row: 3, Column: "wht", Message: "WHT cannot be greater than TotalIncome"},
+			}, report.Problems)
+		}
+	}
+}
+
+func TestTaxController_ValidateCSVTax_InvalidHeaders(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("wht,bonus,wht\n0,0,0\n"))
+	rec := httptest.NewRecorder()
+
+	taxController := NewTaxController(nil)
+
+	if assert.NoError(t, taxController.ValidateCSVTax(e.NewContext(req, rec))) {
+		var report schemas.CSVValidationReport
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report)) {
+			assert.False(t, report.Valid)
+			assert.Equal(t, 1, report.RowCount)
+			assert.Equal(t, []schemas.CSVValidationProblem{
+				{Row: 0, Column: "bonus", Message: "unknown column"},
+				{Row: 0, Column: "wht", Message: "duplicate column"},
+				{Row: 0, Column: "totalincome", Message: "missing required column"},
+			}, report.Problems)
+		}
+	}
+}
+
+func TestTaxController_ValidateCSVTax_ValidFile(t *testing.T) {
+	e := echo.New()
+
+	req := newCSVUploadRequest(t, []byte("totalIncome,wht,donation,k-receipt\n500000,0,0,0\n600000,40000,20000,0\n"))
+	rec := httptest.NewRecorder()
+
+	taxController := NewTaxController(nil)
+
+	if assert.NoError(t, taxController.ValidateCSVTax(e.NewContext(req, rec))) {
+		var report schemas.CSVValidationReport
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report)) {
+			assert.True(t, report.Valid)
+			assert.Equal(t, 2, report.RowCount)
+			assert.Empty(t, report.Problems)
+		}
+	}
+}
+
+func TestTaxController_CalculateCSVTax_RecordsSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(sdktrace.NewTracerProvider()) })
+
+	e := echo.New()
+	req := newCSVUploadRequest(t, []byte("totalIncome,wht,donation\n500000,0,0\n600000,40000,20000\n"))
+	rec := httptest.NewRecorder()
+
+	mockTaxService := new(MockTaxService)
+	mockTaxService.On("CalculateTaxFromCSV", mock.Anything).Return(schemas.CSVResponseV2{}, nil)
+
+	taxController := NewTaxController(mockTaxService)
+	assert.NoError(t, taxController.CalculateCSVTax(e.NewContext(req, rec)))
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		parse, handler := spans[0], spans[1]
+		assert.Equal(t, "parse CSV", parse.Name())
+		assert.Equal(t, "TaxController.CalculateCSVTax", handler.Name())
+		assert.Equal(t, handler.SpanContext().SpanID(), parse.Parent().SpanID())
+		assert.Contains(t, parse.Attributes(), attribute.Int("csv.rows", 2))
+	}
+}
+
+func TestTaxController_CalculateCSVTax_RecordsErrorOnSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(sdktrace.NewTracerProvider()) })
+
+	e := echo.New()
+	req := newCSVUploadRequest(t, []byte("totalIncome,wht,donation\n500000,0,0\n"))
+	rec := httptest.NewRecorder()
+
+	mockTaxService := new(MockTaxService)
+	mockTaxService.On("CalculateTaxFromCSV", mock.Anything).Return(schemas.CSVResponseV2{}, assert.AnError)
+
+	taxController := NewTaxController(mockTaxService)
+	assert.Error(t, taxController.CalculateCSVTax(e.NewContext(req, rec)))
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		handler := spans[1]
+		assert.Equal(t, "TaxController.CalculateCSVTax", handler.Name())
+		assert.Equal(t, codes.Error, handler.Status().Code)
+	}
+}
+
+func TestTaxController_CalculateDetailedTax_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		reqBody  string
+		expected schemas.ErrorResponse
+	}{
+		{
+			"Validation",
+			`{"totalIncome": 500000, "wht": 0, "allowances": [{"allowanceType": "donation", "amount": 100}, {"allowanceType": "k-receipt", "amount": -1}]}`,
+			schemas.ErrorResponse{
+				Code:    schemas.ErrorCodeValidationFailed,
+				Message: "validation errors: Amount for k-receipt must be non-negative",
+				Errors: []schemas.FieldError{{
+					Field: "allowances[1].amount", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "Amount for k-receipt must be non-negative",
+				}},
+			},
+		},
+		{
+			"Wrong JSON type",
+			`{"totalIncome": "a lot", "wht": 0}`,
+			schemas.ErrorResponse{
+				Code:    schemas.ErrorCodeInvalidRequest,
+				Message: "Invalid input data",
+				Errors: []schemas.FieldError{{
+					Field: "totalIncome", Code: schemas.FieldCodeInvalidType, Message: "totalIncome must be a number",
+				}},
+			},
+		},
+		{
+			"Malformed JSON",
+			`{"totalIncome":`,
+			schemas.ErrorResponse{Code: schemas.ErrorCodeInvalidRequest, Message: "Invalid input data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(tt.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			err := NewTaxController(nil).CalculateDetailedTax(e.NewContext(req, httptest.NewRecorder()))
+
+			if assert.IsType(t, &echo.HTTPError{}, err) {
+				assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tt.expected, err.(*echo.HTTPError).Message)
+			}
+		})
+	}
+}
+
+func TestTaxController_CalculateCSVTax_FieldErrors(t *testing.T) {
+	e := echo.New()
+	req := newCSVUploadRequest(t, []byte("totalIncome,wht\n500000,0\nabc,0\n"))
+
+	err := NewTaxController(nil).CalculateCSVTax(e.NewContext(req, httptest.NewRecorder()))
+
+	if assert.IsType(t, &echo.HTTPError{}, err) {
+		response := err.(*echo.HTTPError).Message.(schemas.ErrorResponse)
+		assert.Equal(t, schemas.ErrorCodeInvalidCSV, response.Code)
+		assert.Equal(t, []schemas.FieldError{{
+			Field: "totalincome", Row: 2, Code: schemas.FieldCodeInvalidNumber, Value: "abc", Message: "invalid number",
+		}}, response.Errors)
+	}
+}
+
+func TestTaxController_CalculateDetailedTax_ThaiValidationErrors(t *testing.T) {
+	e := echo.New()
+	reqBody := `{"totalIncome": 500000, "allowances": [{"allowanceType": "donation", "amount": -1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.Thai))
+
+	err := NewTaxController(nil).CalculateDetailedTax(e.NewContext(req, httptest.NewRecorder()))
+
+	if assert.IsType(t, &echo.HTTPError{}, err) {
+		response := err.(*echo.HTTPError).Message.(schemas.ErrorResponse)
+		assert.Equal(t, "ข้อมูลไม่ผ่านการตรวจสอบ: ต้องระบุภาษีหัก ณ ที่จ่าย, จำนวนเงินของ donation ต้องไม่ติดลบ", response.Message)
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "wht", Code: schemas.FieldCodeRequired, Message: "ต้องระบุภาษีหัก ณ ที่จ่าย"},
+			{Field: "allowances[0].amount", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "จำนวนเงินของ donation ต้องไม่ติดลบ"},
+		}, response.Errors)
+	}
+}
+
+func TestTaxController_CalculateTaxV2_AlwaysReturnsTaxAndRefund(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	upperBound, distance := 150000.0, 60000.0
+	breakdown := schemas.TaxCalculationV2Response{
+		TaxRefund:       25000,
+		TaxableIncome:   90000,
+		TotalDeductions: 60000,
+		WHTCredited:     25000,
+		NetTax:          -25000,
+		TaxRates:        schemas.TaxRates{DistanceToNextBracket: &distance},
+		Deductions:      []schemas.DeductionLine{{AllowanceType: "personal", Claimed: 60000, Allowed: 60000, Reason: schemas.DeductionReasonFixed}},
+		TaxLevel:        []schemas.TaxBracket{{Level: "0-150,000", UpperBound: &upperBound}},
+	}
+	mockService.On("CalculateTaxBreakdown", 150000.0, 25000.0, []schemas.Allowance(nil)).Return(breakdown, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/calculations", strings.NewReader(`{"totalIncome": 150000, "wht": 25000}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.CalculateTaxV2(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"tax": 0,
+			"taxRefund": 25000,
+			"taxableIncome": 90000,
+			"totalDeductions": 60000,
+			"grossTax": 0,
+			"whtCredited": 25000,
+			"netTax": -25000,
+			"marginalRate": 0,
+			"effectiveRate": 0,
+			"distanceToNextBracket": 60000,
+			"deductions": [{"allowanceType": "personal", "claimed": 60000, "allowed": 60000, "cap": null, "reason": "fixed", "explanation": ""}],
+			"taxLevel": [{"level": "0-150,000", "lowerBound": 0, "upperBound": 150000, "rate": 0, "tax": 0}]
+		}`, rec.Body.String())
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_GrossUp(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	result := schemas.GrossUpResponse{TargetNetIncome: 400000, GrossIncome: 421111.11, NetIncome: 400000, WHTPolicy: "rate"}
+	mockService.On("GrossUp", 400000.0, []schemas.Allowance(nil), "rate", 0.03).Return(result, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/gross-up", strings.NewReader(`{"targetNetIncome": 400000, "whtPolicy": "rate", "whtRate": 0.03}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.GrossUp(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp schemas.GrossUpResponse
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, result, resp)
+		}
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_GrossUp_DefaultsToFullPolicy(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	mockService.On("GrossUp", 400000.0, []schemas.Allowance(nil), schemas.WHTPolicyFull, 0.0).Return(schemas.GrossUpResponse{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/gross-up", strings.NewReader(`{"targetNetIncome": 400000}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.GrossUp(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_GrossUp_InvalidPolicy(t *testing.T) {
+	e := echo.New()
+	controller := NewTaxController(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/gross-up", strings.NewReader(`{"targetNetIncome": 400000, "whtPolicy": "half"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	err := controller.GrossUp(e.NewContext(req, rec))
+
+	httpErr, ok := err.(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		response := httpErr.Message.(schemas.ErrorResponse)
+		assert.Equal(t, schemas.ErrorCodeValidationFailed, response.Code)
+		if assert.Len(t, response.Errors, 1) {
+			assert.Equal(t, "whtPolicy", response.Errors[0].Field)
+			assert.Equal(t, schemas.FieldCodeInvalidOption, response.Errors[0].Code)
+		}
+	}
+}
+
+func TestTaxController_CompareScenarios(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	comparison := schemas.ScenarioComparisonResponse{
+		ConfigVersion: 1,
+		Base:          schemas.ScenarioResult{Name: "base", Result: schemas.TaxCalculationV2Response{Tax: 11000}},
+		Variants: []schemas.ScenarioResult{{
+			Name:       "max donation",
+			Result:     schemas.TaxCalculationV2Response{TaxRefund: 4000},
+			Difference: &schemas.ScenarioDifference{Tax: -11000, TaxRefund: 4000},
+		}},
+	}
+	mockService.On("CompareScenarios", mock.Anything, mock.Anything).Return(comparison, nil)
+
+	reqBody := `{
+		"base": {"totalIncome": 800000, "wht": 50000},
+		"variants": [{"name": "max donation", "totalIncome": 800000, "wht": 50000, "allowances": [{"allowanceType": "donation", "amount": 100000}]}]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/scenarios/compare", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.CompareScenarios(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp schemas.ScenarioComparisonResponse
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, comparison, resp)
+		}
+	}
+
+	variants := mockService.Calls[0].Arguments.Get(1).([]schemas.ScenarioVariant)
+	if assert.Len(t, variants, 1) {
+		assert.Equal(t, "max donation", variants[0].Name)
+		assert.Equal(t, []schemas.Allowance{{AllowanceType: "donation", Amount: 100000}}, variants[0].Allowances)
+	}
+}
+
+func TestTaxController_OptimizeDeductions(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	result := schemas.DeductionOptimizationResponse{Budget: 100000, Spent: 100000, TaxSaved: 10000, TaxSavedPerBaht: 0.1, SaturationPoint: 150000}
+	mockService.On("OptimizeDeductions", 500000.0, 0.0, []schemas.Allowance(nil), 100000.0, []string{"k-receipt"}).Return(result, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/deductions/optimize", strings.NewReader(`{"totalIncome": 500000, "wht": 0, "budget": 100000, "items": ["k-receipt"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.OptimizeDeductions(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp schemas.DeductionOptimizationResponse
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, result, resp)
+		}
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_CalculateWithholding(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	result := schemas.WithholdingResponse{AnnualIncome: 700000, AnnualTax: 56000, TotalWithheld: 56000}
+	payments := []schemas.MonthlyPayment{{Month: 4, Bonus: 100000}}
+	mockService.On("CalculateWithholding", 50000.0, payments, []schemas.Allowance(nil)).Return(result, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/withholding", strings.NewReader(`{"monthlySalary": 50000, "payments": [{"month": 4, "bonus": 100000}]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.CalculateWithholding(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp schemas.WithholdingResponse
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, result, resp)
+		}
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_ProjectTax(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	result := schemas.ProjectionResponse{
+		Years:          []schemas.ProjectionYear{{Year: 2025, Income: 600000, ConfigSource: schemas.ProjectionConfigLatest, ConfigName: "MainConfig", TaxRefund: 7000, CumulativeTaxRefund: 7000}},
+		TotalIncome:    600000,
+		TotalTaxRefund: 7000,
+	}
+	plan := []schemas.YearAllowances{{Year: 2026, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: 40000}}}}
+	mockService.On("ProjectTax", 2025, 600000.0, 0.05, 0.07, plan).Return(result, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/projections", strings.NewReader(
+		`{"startYear": 2025, "startingIncome": 600000, "growthRate": 0.05, "whtRate": 0.07, "plan": [{"year": 2026, "allowances": [{"allowanceType": "donation", "amount": 40000}]}]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.ProjectTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp schemas.ProjectionResponse
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+			assert.Equal(t, result, resp)
+		}
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestTaxController_ProjectTax_DefaultsToCurrentYear(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockTaxService)
+	controller := NewTaxController(mockService)
+
+	mockService.On("ProjectTax", time.Now().Year(), 600000.0, 0.0, 0.0, []schemas.YearAllowances(nil)).Return(schemas.ProjectionResponse{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tax/projections", strings.NewReader(`{"startingIncome": 600000}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, controller.ProjectTax(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/message"
+)
+
+const (
+	csvColumnTotalIncome = "totalincome"
+	csvColumnWHT         = "wht"
+)
+
+// defaultCSVHeaderAliases lists the alternative headers, English and Thai,
+// accepted for each canonical column. Extra aliases can be configured through
+// CSV_HEADER_ALIASES.
+var defaultCSVHeaderAliases = map[string]string{
+	"total income":    csvColumnTotalIncome,
+	"total_income":    csvColumnTotalIncome,
+	"income":          csvColumnTotalIncome,
+	"รายได้รวม":       csvColumnTotalIncome,
+	"รายได้":          csvColumnTotalIncome,
+	"withholding tax": csvColumnWHT,
+	"withholding_tax": csvColumnWHT,
+	"ภาษีหัก ณ ที่จ่าย": csvColumnWHT,
+	"ภาษีที่หักไว้":     csvColumnWHT,
+	"เงินบริจาค":        "donation",
+	"บริจาค":            "donation",
+	"kreceipt":          "k-receipt",
+	"k_receipt":         "k-receipt",
+	"k receipt":         "k-receipt",
+	"ช้อปลดภาษี":        "k-receipt",
+	"life insurance":    "insurance",
+	"เบี้ยประกันชีวิต":  "insurance",
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var thousandsSeparatedNumber = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+// csvHeaderError reports a problem with the header line of a CSV file.
+type csvHeaderError struct {
+	Column  string
+	Code    string
+	Message string
+}
+
+func (e *csvHeaderError) Error() string {
+	return fmt.Sprintf("%s '%s'", e.Message, e.Column)
+}
+
+func (e *csvHeaderError) localize(printer *message.Printer) string {
+	return printer.Sprintf("%s '%s'", printer.Sprintf(e.Message), e.Column)
+}
+
+// csvFieldError reports a value that could not be read from a CSV cell.
+type csvFieldError struct {
+	Row    int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *csvFieldError) Error() string {
+	return fmt.Sprintf("row %d, column %s: invalid value %q", e.Row, e.Column, e.Value)
+}
+
+func (e *csvFieldError) localize(printer *message.Printer) string {
+	return printer.Sprintf("row %d, column %s: invalid value %q", e.Row, e.Column, e.Value)
+}
+
+func (e *csvFieldError) Unwrap() error {
+	return e.Err
+}
+
+// newTaxCSVReader prepares a csv.Reader for an uploaded file. It strips a UTF-8
+// BOM, decodes TIS-620 files to UTF-8, and detects whether the file is comma,
+// semicolon or tab delimited from its header line.
+func newTaxCSVReader(file io.Reader) (*csv.Reader, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		// TIS-620 is a subset of Windows-874, so the Windows-874 decoder covers both.
+		if data, err = charmap.Windows874.NewDecoder().Bytes(data); err != nil {
+			return nil, err
+		}
+	}
+
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.Comma = detectCSVDelimiter(data)
+	csvReader.TrimLeadingSpace = true
+	return csvReader, nil
+}
+
+// detectCSVDelimiter picks the most frequent of ',', ';' and '\t' found
+// outside quotes on the header line, defaulting to ','.
+func detectCSVDelimiter(data []byte) rune {
+	counts := map[rune]int{}
+	inQuotes := false
+	for _, r := range string(data) {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if r == '\n' || r == '\r' {
+			break
+		}
+		if r == ',' || r == ';' || r == '\t' {
+			counts[r]++
+		}
+	}
+
+	delimiter := ','
+	for _, candidate := range []rune{';', '\t'} {
+		if counts[candidate] > counts[delimiter] {
+			delimiter = candidate
+		}
+	}
+	return delimiter
+}
+
+// resolveCSVHeaders maps each header to its canonical column name using the
+// controller's aliases. Besides totalIncome and wht, every column is an
+// allowance named after its type, which must be one of
+// domains.AllowanceTypes. The returned columns are in header order; every
+// unknown, duplicate or missing column is reported.
+func (tc *TaxController) resolveCSVHeaders(headers []string) ([]string, []*csvHeaderError) {
+	var errs []*csvHeaderError
+	columns := make([]string, len(headers))
+	seen := make(map[string]bool)
+	for i, header := range headers {
+		column := tc.canonicalCSVColumn(header)
+		columns[i] = column
+		if column != csvColumnTotalIncome && column != csvColumnWHT && !domains.IsAllowanceType(column) {
+			errs = append(errs, &csvHeaderError{Column: strings.TrimSpace(header), Code: schemas.FieldCodeUnknownColumn, Message: "unknown column"})
+			continue
+		}
+		if seen[column] {
+			errs = append(errs, &csvHeaderError{Column: column, Code: schemas.FieldCodeDuplicateColumn, Message: "duplicate column"})
+		}
+		seen[column] = true
+	}
+
+	if !seen[csvColumnTotalIncome] {
+		errs = append(errs, &csvHeaderError{Column: csvColumnTotalIncome, Code: schemas.FieldCodeMissingColumn, Message: "missing required column"})
+	}
+	return columns, errs
+}
+
+func (tc *TaxController) canonicalCSVColumn(header string) string {
+	normalizedHeader := strings.ToLower(strings.TrimSpace(header))
+	if column, ok := tc.csvHeaderAliases[normalizedHeader]; ok {
+		return column
+	}
+	if column, ok := defaultCSVHeaderAliases[normalizedHeader]; ok {
+		return column
+	}
+	return normalizedHeader
+}
+
+// parseCSVRecord converts one CSV row into a tax record, with an allowance for
+// every allowance column in column order. row is the 1-based data row number
+// used in error messages. Every cell that is not a number is reported, in
+// column order.
+func parseCSVRecord(record []string, columns []string, row int) (schemas.CSVObjectFormat, []*csvFieldError) {
+	var taxRecord schemas.CSVObjectFormat
+	var errs []*csvFieldError
+	for i, column := range columns {
+		value, err := parseAndValidateFloat(record[i])
+		if err != nil {
+			errs = append(errs, &csvFieldError{Row: row, Column: column, Value: record[i], Err: err})
+			continue
+		}
+
+		switch column {
+		case csvColumnTotalIncome:
+			taxRecord.TotalIncome = value
+		case csvColumnWHT:
+			taxRecord.WHT = value
+		default:
+			taxRecord.Allowances = append(taxRecord.Allowances, schemas.Allowance{AllowanceType: column, Amount: value})
+		}
+	}
+	return taxRecord, errs
+}
+
+// parseAndValidateFloat parses a CSV cell as a number. Thousands separators
+// such as "1,200,000.50" are accepted.
+func parseAndValidateFloat(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if thousandsSeparatedNumber.MatchString(value) {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return floatValue, nil
+}
@@ -1,16 +1,28 @@
 package endpoints
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"github.com/thitiphum-bluesage/assessment-tax/config"
 	_ "github.com/thitiphum-bluesage/assessment-tax/docs"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/repository"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/endpoints/controllers"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
-func Router(e *echo.Echo, taxControllerr *controllers.TaxController, adminController *controllers.AdminController, cfg *config.Config) {
+func Router(e *echo.Echo, taxControllerr *controllers.TaxController, adminController *controllers.AdminController, healthController *controllers.HealthController, idempotencyRepo repository.IdempotencyRepositoryInterface, cfg *config.Config) {
+
+	e.HTTPErrorHandler = middleware.ErrorHandler
+
+	e.Use(middleware.RequestID())
+	e.Use(middleware.Locale())
+	e.Use(middleware.Metrics())
+	e.Use(otelecho.Middleware(cfg.ServiceName, otelecho.WithSkipper(middleware.SkipProbes)))
+	e.Use(middleware.AccessLog(slog.Default()))
 
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Hello, Go Bootcamp!")
@@ -23,14 +35,69 @@ func Router(e *echo.Echo, taxControllerr *controllers.TaxController, adminContro
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	// Probes for Kubernetes
+	e.GET("/healthz", healthController.Liveness)
+	e.GET("/readyz", healthController.Readiness)
+
+	idempotency := middleware.Idempotency(idempotencyRepo, cfg.IdempotencyTTL)
+	adminAuth := middleware.BasicAuth(cfg)
+
+	// v1 keeps the original response shapes and is also served without a
+	// version prefix for existing clients. Both are deprecated in favour of v2.
+	for _, prefix := range []string{"", "/v1"} {
+		deprecated := middleware.Deprecated(prefix, "/v2")
+		mountAPI(e, prefix, taxControllerr, adminController, versionHandlers{
+			calculate:       taxControllerr.CalculateDetailedTax,
+			uploadCSV:       taxControllerr.CalculateCSVTax,
+			deductionConfig: adminController.GetDeductionConfig,
+		}, idempotency, adminAuth, deprecated)
+	}
+	mountAPI(e, "/v2", taxControllerr, adminController, versionHandlers{
+		calculate:       taxControllerr.CalculateTaxV2,
+		uploadCSV:       taxControllerr.CalculateCSVTaxV2,
+		deductionConfig: adminController.GetDeductionConfigV2,
+	}, idempotency, adminAuth)
+
+	// Endpoints added since v2 are only served under /v2.
+	v2TaxGroup := e.Group("/v2/tax")
+	v2TaxGroup.POST("/gross-up", taxControllerr.GrossUp)
+	v2TaxGroup.POST("/scenarios/compare", taxControllerr.CompareScenarios)
+	v2TaxGroup.POST("/deductions/optimize", taxControllerr.OptimizeDeductions)
+	v2TaxGroup.POST("/withholding", taxControllerr.CalculateWithholding)
+	v2TaxGroup.POST("/projections", taxControllerr.ProjectTax)
+
+	v2AdminGroup := e.Group("/v2/admin", adminAuth)
+	v2AdminGroup.POST("/deductions/insurance", adminController.UpdateInsuranceDeduction)
+	v2AdminGroup.POST("/deductions/rmf", adminController.UpdateRMFDeduction)
+	v2AdminGroup.POST("/deductions/ssf", adminController.UpdateSSFDeduction)
+	v2AdminGroup.GET("/deductions/tax-years", adminController.ListTaxYearConfigs)
+	v2AdminGroup.POST("/deductions/tax-years/:year", adminController.CreateTaxYearConfig)
+	v2AdminGroup.PUT("/deductions/tax-years/:year", adminController.UpdateTaxYearConfig)
+}
+
+// versionHandlers are the handlers that differ between API versions.
+type versionHandlers struct {
+	calculate       echo.HandlerFunc
+	uploadCSV       echo.HandlerFunc
+	deductionConfig echo.HandlerFunc
+}
+
+// mountAPI registers the tax and admin routes under prefix, using handlers
+// for the routes whose responses differ between versions.
+func mountAPI(e *echo.Echo, prefix string, taxController *controllers.TaxController, adminController *controllers.AdminController, handlers versionHandlers, idempotency, adminAuth echo.MiddlewareFunc, m ...echo.MiddlewareFunc) {
 	// Group for tax-related routes
-	taxGroup := e.Group("/tax")
-	taxGroup.POST("/calculations", taxControllerr.CalculateDetailedTax)
-	taxGroup.POST("/calculations/upload-csv", taxControllerr.CalculateCSVTax)
+	taxGroup := e.Group(prefix+"/tax", m...)
+	taxGroup.POST("/calculations", handlers.calculate)
+	taxGroup.POST("/calculations/upload-csv", handlers.uploadCSV, idempotency)
+	taxGroup.POST("/calculations/upload-csv/validate", taxController.ValidateCSVTax)
+	taxGroup.POST("/calculations/batch", taxController.CalculateBatchTax, idempotency)
 
 	// Group for admin-related routes
-	adminGroup := e.Group("/admin")
-	adminGroup.Use(middleware.BasicAuth(cfg))
+	adminGroup := e.Group(prefix+"/admin", m...)
+	adminGroup.Use(adminAuth)
+	adminGroup.GET("/deductions", handlers.deductionConfig)
 	adminGroup.POST("/deductions/personal", adminController.UpdatePersonalDeduction)
 	adminGroup.POST("/deductions/k-receipt", adminController.UpdateKReceiptDeduction)
 }
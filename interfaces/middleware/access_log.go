@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccessLog writes one log line per request with its route, status, latency
+// and client. Only the path is logged, never the query string or body, which
+// may contain income data.
+func AccessLog(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := responseStatus(c, err)
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", c.Request().Method),
+				slog.String("route", c.Path()),
+				slog.String("path", c.Request().URL.Path),
+				slog.Int("status", status),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("client", c.RealIP()),
+				slog.String("user_agent", c.Request().UserAgent()),
+			}
+			if err != nil && status >= http.StatusInternalServerError {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			logger.LogAttrs(c.Request().Context(), level, "request", attrs...)
+			return err
+		}
+	}
+}
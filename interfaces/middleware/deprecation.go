@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerDeprecation = "Deprecation"
+	headerLink        = "Link"
+)
+
+// Deprecated marks the responses of routes under prefix as deprecated and
+// points clients at the same path under successor, e.g. /v1/tax/calculations
+// at /v2/tax/calculations.
+func Deprecated(prefix, successor string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := strings.TrimPrefix(c.Request().URL.Path, prefix)
+			c.Response().Header().Set(headerDeprecation, "true")
+			c.Response().Header().Add(headerLink, "<"+successor+path+`>; rel="successor-version"`)
+			return next(c)
+		}
+	}
+}
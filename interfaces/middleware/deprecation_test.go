@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		path   string
+		link   string
+	}{
+		{"Versioned", "/v1", "/v1/tax/calculations", `</v2/tax/calculations>; rel="successor-version"`},
+		{"Unversioned", "", "/admin/deductions", `</v2/admin/deductions>; rel="successor-version"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			handler := Deprecated(tt.prefix, "/v2")(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, handler(e.NewContext(httptest.NewRequest(http.MethodGet, tt.path, nil), rec)))
+			assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+			assert.Equal(t, tt.link, rec.Header().Get("Link"))
+		})
+	}
+}
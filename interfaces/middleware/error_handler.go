@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+)
+
+// statusErrorCodes gives errors that do not carry a schemas.ErrorResponse, such
+// as Echo's own 404 and 405, a code derived from their status.
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            schemas.ErrorCodeInvalidRequest,
+	http.StatusUnauthorized:          schemas.ErrorCodeUnauthorized,
+	http.StatusForbidden:             schemas.ErrorCodeForbidden,
+	http.StatusNotFound:              schemas.ErrorCodeNotFound,
+	http.StatusMethodNotAllowed:      schemas.ErrorCodeMethodNotAllowed,
+	http.StatusRequestEntityTooLarge: schemas.ErrorCodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  schemas.ErrorCodeUnsupportedMediaType,
+	http.StatusPreconditionRequired:  schemas.ErrorCodePreconditionRequired,
+	http.StatusTooManyRequests:       schemas.ErrorCodeTooManyRequests,
+	http.StatusServiceUnavailable:    schemas.ErrorCodeServiceUnavailable,
+}
+
+// ErrorHandler is Echo's HTTPErrorHandler. It renders every error as a
+// schemas.ErrorResponse: an *echo.HTTPError carrying one is written as is,
+// other HTTP errors keep their message, translated when it is in the message
+// catalogues, and get a code for their status, and any other error becomes a
+// 500 without exposing its message.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	ctx := c.Request().Context()
+	status := http.StatusInternalServerError
+	response := schemas.ErrorResponse{Message: i18n.Translate(ctx, http.StatusText(status))}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+		switch message := httpErr.Message.(type) {
+		case schemas.ErrorResponse:
+			response = message
+		case string:
+			response = schemas.ErrorResponse{Message: i18n.Translate(ctx, message)}
+		default:
+			response = schemas.ErrorResponse{Message: i18n.Translate(ctx, http.StatusText(status))}
+		}
+	}
+	if response.Code == "" {
+		response.Code = errorCodeForStatus(status)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, response)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to write error response", "error", err)
+	}
+}
+
+func errorCodeForStatus(status int) string {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return schemas.ErrorCodeInternal
+	}
+	return schemas.ErrorCodeInvalidRequest
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+)
+
+func TestErrorHandler(t *testing.T) {
+	validationFailed := schemas.ErrorResponse{
+		Code:    schemas.ErrorCodeValidationFailed,
+		Message: "validation errors: WHT is required",
+		Errors:  []schemas.FieldError{{Field: "wht", Code: schemas.FieldCodeRequired, Message: "WHT is required"}},
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		expected schemas.ErrorResponse
+	}{
+		{
+			"Envelope is written as is",
+			echo.NewHTTPError(http.StatusBadRequest, validationFailed),
+			http.StatusBadRequest,
+			validationFailed,
+		},
+		{
+			"String message gets a code for its status",
+			echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: Incorrect credentials"),
+			http.StatusUnauthorized,
+			schemas.ErrorResponse{Code: schemas.ErrorCodeUnauthorized, Message: "Unauthorized: Incorrect credentials"},
+		},
+		{
+			"Echo's own errors",
+			echo.ErrNotFound,
+			http.StatusNotFound,
+			schemas.ErrorResponse{Code: schemas.ErrorCodeNotFound, Message: "Not Found"},
+		},
+		{
+			"Unexpected errors do not leak their message",
+			assert.AnError,
+			http.StatusInternalServerError,
+			schemas.ErrorResponse{Code: schemas.ErrorCodeInternal, Message: "Internal Server Error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			ErrorHandler(tt.err, c)
+
+			assert.Equal(t, tt.status, rec.Code)
+			var response schemas.ErrorResponse
+			if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response)) {
+				assert.Equal(t, tt.expected, response)
+			}
+		})
+	}
+}
+
+func TestErrorHandler_UnmatchedRoute(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"code":"not_found","message":"Not Found"}`, rec.Body.String())
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/repository"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes a route safe to retry. When a request carries an
+// Idempotency-Key header, the key is reserved before the handler runs and the
+// response is stored together with a hash of the request. A retry with the
+// same key and payload gets the stored response back without running the
+// handler again, or 409 while the first request is still running; the same
+// key with a different payload is rejected with 422. Keys expire after ttl.
+// Requests without the header are passed through unchanged.
+func Idempotency(repo repository.IdempotencyRepositoryInterface, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Failed to read the request body")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			requestHash := fingerprintRequest(c.Request(), body)
+			ctx := c.Request().Context()
+
+			now := time.Now()
+			reserved, err := repo.Reserve(ctx, &domains.IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: now}, now.Add(-ttl))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+			if !reserved {
+				return replayIdempotent(c, repo, key, requestHash)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			err = next(c)
+			status := c.Response().Status
+			if err != nil || status >= http.StatusInternalServerError {
+				// Errors are rendered later by Echo's error handler, so there is no
+				// response to store; releasing the key lets the client retry with it.
+				if releaseErr := repo.Release(ctx, key, requestHash); releaseErr != nil {
+					slog.ErrorContext(ctx, "Failed to release idempotency key", "idempotency_key", key, "error", releaseErr)
+				}
+				return err
+			}
+
+			err = repo.Complete(ctx, &domains.IdempotencyRecord{
+				Key:          key,
+				RequestHash:  requestHash,
+				StatusCode:   status,
+				ContentType:  c.Response().Header().Get(echo.HeaderContentType),
+				ResponseBody: recorder.body.Bytes(),
+			})
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to store response for idempotency key", "idempotency_key", key, "error", err)
+			}
+			return nil
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already taken.
+func replayIdempotent(c echo.Context, repo repository.IdempotencyRepositoryInterface, key, requestHash string) error {
+	record, err := repo.FindByKey(c.Request().Context(), key)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	switch {
+	case record != nil && record.RequestHash != requestHash:
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, schemas.ErrorResponse{
+			Code:    schemas.ErrorCodeIdempotencyKeyReused,
+			Message: "Idempotency-Key has already been used with a different request",
+		})
+	case record == nil || record.Pending():
+		// A record that is gone was released by a failed request in the
+		// meantime; either way the client should retry later.
+		return echo.NewHTTPError(http.StatusConflict, schemas.ErrorResponse{
+			Code:    schemas.ErrorCodeIdempotencyRequestInProgress,
+			Message: "A request with this Idempotency-Key is still being processed",
+		})
+	}
+	c.Response().Header().Set(HeaderIdempotencyReplayed, "true")
+	return c.Blob(record.StatusCode, record.ContentType, record.ResponseBody)
+}
+
+// fingerprintRequest hashes the method, path and payload of a request. For
+// multipart uploads the parts are hashed instead of the raw body, because
+// clients pick a new boundary on every retry.
+func fingerprintRequest(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.Path+"\n")
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		if hashMultipart(hash, body, params["boundary"]) == nil {
+			return hex.EncodeToString(hash.Sum(nil))
+		}
+		hash.Reset()
+		io.WriteString(hash, req.Method+" "+req.URL.Path+"\n")
+	}
+
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func hashMultipart(w io.Writer, body []byte, boundary string) error {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, part.FormName()+"\n"+part.FileName()+"\n")
+		if _, err := io.Copy(w, part); err != nil {
+			return err
+		}
+	}
+}
+
+// responseRecorder keeps a copy of everything written to the response while
+// still streaming it to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/repository"
+	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+)
+
+func newIdempotentHandler(calls *int) echo.HandlerFunc {
+	handler := func(c echo.Context) error {
+		*calls++
+		return c.JSON(http.StatusOK, map[string]int{"call": *calls})
+	}
+	return Idempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour)(handler)
+}
+
+func serveIdempotent(handler echo.HandlerFunc, key, contentType, body string) (*httptest.ResponseRecorder, error) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	return rec, handler(e.NewContext(req, rec))
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls)
+
+	first, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	replay, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get(HeaderIdempotencyReplayed))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, first.Header().Get(echo.HeaderContentType), replay.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_RejectsDifferentPayload(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls)
+
+	_, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.NoError(t, err)
+
+	_, err = serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 900000, "wht": 0}`)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, err.(*echo.HTTPError).Code)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls)
+
+	for i := 0; i < 2; i++ {
+		rec, err := serveIdempotent(handler, "", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+		assert.NoError(t, err)
+		assert.Empty(t, rec.Header().Get(HeaderIdempotencyReplayed))
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_MultipartIgnoresBoundary(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls)
+
+	upload := func(boundary string) (*httptest.ResponseRecorder, error) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		assert.NoError(t, writer.SetBoundary(boundary))
+		part, err := writer.CreateFormFile("taxFile", "taxes.csv")
+		assert.NoError(t, err)
+		part.Write([]byte("totalIncome,wht\n500000,0\n"))
+		assert.NoError(t, writer.Close())
+		return serveIdempotent(handler, "upload-1", writer.FormDataContentType(), body.String())
+	}
+
+	_, err := upload("first-boundary")
+	assert.NoError(t, err)
+	replay, err := upload("second-boundary")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", replay.Header().Get(HeaderIdempotencyReplayed))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_RequestInProgress(t *testing.T) {
+	repo := repository.NewInMemoryIdempotencyRepository()
+	calls := 0
+	var handler echo.HandlerFunc
+	handler = Idempotency(repo, time.Hour)(func(c echo.Context) error {
+		calls++
+		// Retries that arrive while the first request is still running must
+		// not run the handler again.
+		_, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
+			assert.Equal(t, schemas.ErrorCodeIdempotencyRequestInProgress, err.(*echo.HTTPError).Message.(schemas.ErrorResponse).Code)
+		}
+		_, err = serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 900000, "wht": 0}`)
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, err.(*echo.HTTPError).Code)
+		}
+		return c.JSON(http.StatusOK, map[string]int{"call": calls})
+	})
+
+	rec, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, calls)
+
+	replay, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", replay.Header().Get(HeaderIdempotencyReplayed))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_FailureReleasesKey(t *testing.T) {
+	calls := 0
+	handler := Idempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour)(func(c echo.Context) error {
+		calls++
+		if calls == 1 {
+			return echo.NewHTTPError(http.StatusInternalServerError, "database is down")
+		}
+		return c.JSON(http.StatusOK, map[string]int{"call": calls})
+	})
+
+	_, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.Error(t, err)
+
+	rec, err := serveIdempotent(handler, "retry-1", "application/x-ndjson", `{"totalIncome": 500000, "wht": 0}`)
+	assert.NoError(t, err)
+	assert.Empty(t, rec.Header().Get(HeaderIdempotencyReplayed))
+	assert.Equal(t, 2, calls)
+}
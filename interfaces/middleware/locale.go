@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"golang.org/x/text/language"
+)
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+// Locale stores the language picked from Accept-Language in the request
+// context, where controllers and services look it up to localize messages and
+// tax level labels. The chosen language is returned in Content-Language.
+func Locale() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Add(echo.HeaderVary, headerAcceptLanguage)
+
+			locale := i18n.Match(c.Request().Header.Get(headerAcceptLanguage))
+			if locale != language.Und {
+				c.Response().Header().Set(headerContentLanguage, locale.String())
+				c.SetRequest(c.Request().WithContext(i18n.WithLocale(c.Request().Context(), locale)))
+			}
+			return next(c)
+		}
+	}
+}
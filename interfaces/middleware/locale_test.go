@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/i18n"
+	"golang.org/x/text/language"
+)
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		name            string
+		acceptLanguage  string
+		locale          language.Tag
+		contentLanguage string
+	}{
+		{"No header", "", language.Und, ""},
+		{"Thai", "th-TH,th;q=0.9", language.Thai, "th"},
+		{"English", "en-GB", language.English, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			var locale language.Tag
+			handler := Locale()(func(c echo.Context) error {
+				locale = i18n.Locale(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, handler(e.NewContext(req, rec)))
+			assert.Equal(t, tt.locale, locale)
+			assert.Equal(t, tt.contentLanguage, rec.Header().Get("Content-Language"))
+			assert.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestErrorHandler_TranslatesMessages(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	e.Use(Locale())
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/nowhere", nil)
+	req.Header.Set("Accept-Language", "th")
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"code":"not_found","message":"ไม่พบสิ่งที่ร้องขอ"}`, rec.Body.String())
+}
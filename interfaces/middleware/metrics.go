@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
+)
+
+// Metrics records the count, status code and latency of every request, labelled
+// with the route pattern rather than the raw path so that path parameters do
+// not create new series.
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			status := responseStatus(c, err)
+
+			metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// responseStatus is the status code the client will receive. Errors returned
+// by handlers are only written by Echo's error handler after the middleware
+// chain, so their code has to be taken from the error itself.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
+)
+
+func TestMetrics_RecordsRouteAndStatus(t *testing.T) {
+	e := echo.New()
+	e.Use(Metrics())
+	e.GET("/items/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "not found")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	okCounter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/items/:id", "200")
+	notFoundCounter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/items/:id", "404")
+	okBefore, notFoundBefore := testutil.ToFloat64(okCounter), testutil.ToFloat64(notFoundCounter)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/missing"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, okBefore+2, testutil.ToFloat64(okCounter))
+	assert.Equal(t, notFoundBefore+1, testutil.ToFloat64(notFoundCounter))
+}
+
+func TestMetrics_UnexpectedErrorCountsAs500(t *testing.T) {
+	e := echo.New()
+	e.Use(Metrics())
+	e.GET("/boom", func(c echo.Context) error {
+		return assert.AnError
+	})
+
+	counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/boom", "500")
+	before := testutil.ToFloat64(counter)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
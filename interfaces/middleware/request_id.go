@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/logging"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID, reusing a well-formed X-Request-ID sent
+// by the client or a proxy. The ID is echoed in the response header and stored
+// in the request context, where the logger picks it up.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			requestID := c.Request().Header.Get(echo.HeaderXRequestID)
+			if !isValidRequestID(requestID) {
+				requestID = newRequestID()
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+			c.SetRequest(c.Request().WithContext(logging.WithRequestID(c.Request().Context(), requestID)))
+			return next(c)
+		}
+	}
+}
+
+// isValidRequestID only accepts short printable ASCII IDs, so a client cannot
+// inject control characters or huge values into the logs.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
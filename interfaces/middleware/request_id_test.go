@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{"Generates an ID", "", false},
+		{"Reuses the client's ID", "abc-123", true},
+		{"Replaces IDs with control characters", "abc\n123", false},
+		{"Replaces overlong IDs", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(RequestID())
+			var seen string
+			e.GET("/", func(c echo.Context) error {
+				seen = logging.RequestID(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			header := rec.Header().Get(echo.HeaderXRequestID)
+			assert.NotEmpty(t, header)
+			assert.Equal(t, header, seen)
+			if tt.reused {
+				assert.Equal(t, tt.incoming, header)
+			} else {
+				assert.NotEqual(t, tt.incoming, header)
+				assert.Len(t, header, 32)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := echo.New()
+	e.Use(RequestID(), AccessLog(logging.New(buf, slog.LevelInfo)))
+	e.POST("/tax/calculations", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid input data")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations?totalIncome=500000", strings.NewReader(`{"totalIncome":500000}`))
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	if assert.NoError(t, json.Unmarshal(buf.Bytes(), &line)) {
+		assert.Equal(t, "INFO", line["level"])
+		assert.Equal(t, "req-1", line["request_id"])
+		assert.Equal(t, "/tax/calculations", line["route"])
+		assert.Equal(t, "/tax/calculations", line["path"])
+		assert.Equal(t, 400.0, line["status"])
+		assert.Equal(t, "203.0.113.7", line["client"])
+		assert.Contains(t, line, "latency_ms")
+	}
+	assert.NotContains(t, buf.String(), "500000")
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SkipProbes skips requests from health probes, metric scrapes and the API
+// documentation, which would otherwise drown out real traffic in traces.
+func SkipProbes(c echo.Context) bool {
+	path := c.Request().URL.Path
+	switch path {
+	case "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return strings.HasPrefix(path, "/swagger/")
+}
@@ -0,0 +1,60 @@
+package schemas
+
+// Error codes are part of the API contract: clients branch on them instead of
+// on the message, which may be reworded at any time.
+const (
+	ErrorCodeInvalidRequest               = "invalid_request"
+	ErrorCodeValidationFailed             = "validation_failed"
+	ErrorCodeInvalidCSV                   = "invalid_csv"
+	ErrorCodeUnauthorized                 = "unauthorized"
+	ErrorCodeForbidden                    = "forbidden"
+	ErrorCodeNotFound                     = "not_found"
+	ErrorCodeMethodNotAllowed             = "method_not_allowed"
+	ErrorCodeConfigVersionConflict        = "config_version_conflict"
+	ErrorCodeTaxYearConfigExists          = "tax_year_config_exists"
+	ErrorCodePreconditionRequired         = "precondition_required"
+	ErrorCodePayloadTooLarge              = "payload_too_large"
+	ErrorCodeUnsupportedMediaType         = "unsupported_media_type"
+	ErrorCodeIdempotencyKeyReused         = "idempotency_key_reused"
+	ErrorCodeIdempotencyRequestInProgress = "idempotency_request_in_progress"
+	ErrorCodeTooManyRequests              = "too_many_requests"
+	ErrorCodeServiceUnavailable           = "service_unavailable"
+	ErrorCodeCanceled                     = "canceled"
+	ErrorCodeInternal                     = "internal_error"
+)
+
+// Field error codes describe what is wrong with a single field.
+const (
+	FieldCodeRequired             = "required"
+	FieldCodeInvalidType          = "invalid_type"
+	FieldCodeInvalidNumber        = "invalid_number"
+	FieldCodeMustBeNonNegative    = "must_be_non_negative"
+	FieldCodeOutOfRange           = "out_of_range"
+	FieldCodeExceedsTotalIncome   = "exceeds_total_income"
+	FieldCodeInvalidAllowanceType = "invalid_allowance_type"
+	FieldCodeDuplicateAllowance   = "duplicate_allowance"
+	FieldCodeInvalidOption        = "invalid_option"
+	FieldCodeDuplicateName        = "duplicate_name"
+	FieldCodeDuplicateMonth       = "duplicate_month"
+	FieldCodeDuplicateYear        = "duplicate_year"
+	FieldCodeUnknownColumn        = "unknown_column"
+	FieldCodeDuplicateColumn      = "duplicate_column"
+	FieldCodeMissingColumn        = "missing_column"
+)
+
+type ErrorResponse struct {
+	Code    string       `json:"code" example:"validation_failed"`
+	Message string       `json:"message" example:"validation errors: WHT cannot be greater than TotalIncome"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError points at the request field a problem was found in. Field is the
+// JSON path of the field, e.g. "allowances[1].amount", or the column name for
+// CSV uploads, where Row is the 1-based data row.
+type FieldError struct {
+	Field   string      `json:"field" example:"allowances[1].amount"`
+	Row     int         `json:"row,omitempty" example:"2"`
+	Code    string      `json:"code" example:"must_be_non_negative"`
+	Value   interface{} `json:"value,omitempty" swaggertype:"string" example:"-500"`
+	Message string      `json:"message" example:"Amount for donation must be non-negative"`
+}
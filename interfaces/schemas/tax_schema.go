@@ -16,6 +16,66 @@ type UpdateKReceiptResponse struct {
 	KReceipt float64 `json:"kReceipt" example:"50000"`
 }
 
+type UpdateInsuranceRequest struct {
+	Amount *float64 `json:"amount" example:"100000.0"`
+}
+
+type UpdateInsuranceResponse struct {
+	Insurance float64 `json:"insurance" example:"100000"`
+}
+
+type UpdateRMFRequest struct {
+	Amount *float64 `json:"amount" example:"500000.0"`
+}
+
+type UpdateRMFResponse struct {
+	RMF float64 `json:"rmf" example:"500000"`
+}
+
+type UpdateSSFRequest struct {
+	Amount *float64 `json:"amount" example:"200000.0"`
+}
+
+type UpdateSSFResponse struct {
+	SSF float64 `json:"ssf" example:"200000"`
+}
+
+type DeductionConfigResponse struct {
+	PersonalDeduction float64 `json:"personalDeduction" example:"60000"`
+	KReceipt          float64 `json:"kReceipt" example:"50000"`
+	Donation          float64 `json:"donation" example:"100000"`
+}
+
+// DeductionConfigV2Response adds the insurance, RMF and SSF caps to the v1
+// deduction configuration.
+type DeductionConfigV2Response struct {
+	DeductionConfigResponse
+	Insurance float64 `json:"insurance" example:"100000"`
+	RMF       float64 `json:"rmf" example:"500000"`
+	SSF       float64 `json:"ssf" example:"200000"`
+}
+
+// TaxYearConfigRequest holds the deduction limits of a tax year. Limits that
+// are left out take their default; the donation limit is fixed.
+type TaxYearConfigRequest struct {
+	PersonalDeduction *float64 `json:"personalDeduction" example:"60000"`
+	KReceipt          *float64 `json:"kReceipt" example:"50000"`
+	Insurance         *float64 `json:"insurance" example:"100000"`
+	RMF               *float64 `json:"rmf" example:"500000"`
+	SSF               *float64 `json:"ssf" example:"200000"`
+}
+
+type TaxYearConfigResponse struct {
+	TaxYear    int    `json:"taxYear" example:"2025"`
+	ConfigName string `json:"configName" example:"TaxYear2025"`
+	Version    int64  `json:"version" example:"1"`
+	DeductionConfigV2Response
+}
+
+type TaxYearConfigsResponse struct {
+	TaxYears []TaxYearConfigResponse `json:"taxYears"`
+}
+
 type Allowance struct {
 	AllowanceType string  `json:"allowanceType" `
 	Amount        float64 `json:"amount" `
@@ -46,11 +106,261 @@ type DetailedTaxCalculationResponse struct {
 	TaxLevel []TaxLevel `json:"taxLevel"`
 }
 
+// TaxCalculationV2Response is the /v2 calculation result. Every field is
+// present whether the taxpayer owes tax or is due a refund, so the result can
+// be explained line by line: income less Deductions is TaxableIncome, the
+// brackets in TaxLevel add up to GrossTax, and GrossTax less WHTCredited is
+// NetTax. A negative NetTax is paid back as TaxRefund.
+type TaxCalculationV2Response struct {
+	Tax             float64 `json:"tax" example:"0"`
+	TaxRefund       float64 `json:"taxRefund" example:"31000"`
+	TaxableIncome   float64 `json:"taxableIncome" example:"340000"`
+	TotalDeductions float64 `json:"totalDeductions" example:"160000"`
+	GrossTax        float64 `json:"grossTax" example:"19000"`
+	WHTCredited     float64 `json:"whtCredited" example:"50000"`
+	NetTax          float64 `json:"netTax" example:"-31000"`
+	TaxRates
+	Deductions []DeductionLine `json:"deductions"`
+	TaxLevel   []TaxBracket    `json:"taxLevel"`
+}
+
+// TaxRates tells a taxpayer which bracket they are in and what they pay
+// overall. MarginalRate is the rate of the bracket taxable income lands in,
+// EffectiveRate is gross tax against gross income, and DistanceToNextBracket
+// is how many baht of taxable income are left before the next bracket's rate
+// applies, or null in the top bracket.
+type TaxRates struct {
+	MarginalRate          float64  `json:"marginalRate" example:"0.1"`
+	EffectiveRate         float64  `json:"effectiveRate" example:"0.038"`
+	DistanceToNextBracket *float64 `json:"distanceToNextBracket" example:"160000"`
+}
+
+// Deduction reasons explain how the allowed amount of a deduction line was
+// reached.
+const (
+	DeductionReasonFixed     = "fixed"
+	DeductionReasonWithinCap = "within_cap"
+	DeductionReasonCapped    = "capped"
+)
+
+// DeductionLine is one deduction: what was claimed, how much of it was
+// allowed, the cap applied and why. The personal deduction is always listed
+// first and has no cap.
+type DeductionLine struct {
+	AllowanceType string   `json:"allowanceType" example:"donation"`
+	Claimed       float64  `json:"claimed" example:"200000"`
+	Allowed       float64  `json:"allowed" example:"100000"`
+	Cap           *float64 `json:"cap" example:"100000"`
+	Reason        string   `json:"reason" example:"capped"`
+	Explanation   string   `json:"explanation" example:"Capped at the maximum of 100,000"`
+}
+
+// TaxBracket is the tax charged on the part of taxable income above
+// LowerBound and up to UpperBound. UpperBound is null for the top bracket.
+type TaxBracket struct {
+	Level      string   `json:"level" example:"150,001-500,000"`
+	LowerBound float64  `json:"lowerBound" example:"150000"`
+	UpperBound *float64 `json:"upperBound" example:"500000"`
+	Rate       float64  `json:"rate" example:"0.1"`
+	Tax        float64  `json:"tax" example:"29000"`
+}
+
+// WHT policies decide how much tax is withheld from the gross income found by
+// a gross-up. They change how the tax is settled, not how much is due.
+const (
+	WHTPolicyFull = "full"
+	WHTPolicyNone = "none"
+	WHTPolicyRate = "rate"
+)
+
+type GrossUpRequest struct {
+	TargetNetIncome *float64    `json:"targetNetIncome" example:"400000"`
+	Allowances      []Allowance `json:"allowances"`
+	WHTPolicy       string      `json:"whtPolicy" example:"full" enums:"full,none,rate"`
+	WHTRate         *float64    `json:"whtRate" example:"0.03"`
+}
+
+// GrossUpResponse is the gross income that leaves TargetNetIncome after tax,
+// with the v2 calculation for that income. NetIncome is GrossIncome less
+// GrossTax and matches the target to the satang.
+type GrossUpResponse struct {
+	TargetNetIncome float64 `json:"targetNetIncome" example:"400000"`
+	GrossIncome     float64 `json:"grossIncome" example:"431111.11"`
+	NetIncome       float64 `json:"netIncome" example:"400000"`
+	WHTPolicy       string  `json:"whtPolicy" example:"full"`
+	TaxCalculationV2Response
+}
+
+type ScenarioComparisonRequest struct {
+	Base     *TaxCalculationRequest `json:"base"`
+	Variants []ScenarioVariant      `json:"variants"`
+}
+
+// ScenarioVariant is a complete tax calculation request with a name, e.g.
+// the base request with a larger donation.
+type ScenarioVariant struct {
+	Name string `json:"name" example:"max donation"`
+	TaxCalculationRequest
+}
+
+// ScenarioComparisonResponse has the v2 calculation of the base and of every
+// variant, all made with the deduction configuration at ConfigVersion.
+type ScenarioComparisonResponse struct {
+	ConfigVersion int64            `json:"configVersion" example:"1"`
+	Base          ScenarioResult   `json:"base"`
+	Variants      []ScenarioResult `json:"variants"`
+}
+
+// ScenarioResult is one calculation of a comparison. Difference is the
+// variant less the base and is left out for the base itself.
+type ScenarioResult struct {
+	Name       string                   `json:"name" example:"max donation"`
+	Result     TaxCalculationV2Response `json:"result"`
+	Difference *ScenarioDifference      `json:"difference,omitempty"`
+}
+
+// ScenarioDifference is how a variant's calculation differs from the base.
+// Negative amounts mean less in the variant.
+type ScenarioDifference struct {
+	Tax             float64    `json:"tax" example:"-4000"`
+	TaxRefund       float64    `json:"taxRefund" example:"0"`
+	TaxableIncome   float64    `json:"taxableIncome" example:"-40000"`
+	TotalDeductions float64    `json:"totalDeductions" example:"40000"`
+	GrossTax        float64    `json:"grossTax" example:"-4000"`
+	TaxLevel        []TaxLevel `json:"taxLevel"`
+}
+
+// DeductionOptimizationRequest is a tax calculation request with a budget to
+// spend on deductible items on top of the allowances already claimed. Items
+// lists the allowance types the budget may go to, in order, and defaults to
+// every type with a cap.
+type DeductionOptimizationRequest struct {
+	TaxCalculationRequest
+	Budget *float64 `json:"budget" example:"200000"`
+	Items  []string `json:"items" example:"donation,k-receipt"`
+}
+
+// DeductionOptimizationResponse is the allocation of a budget that gives the
+// lowest tax. Spending past SaturationPoint does not reduce tax any further,
+// because every cap is used or taxable income is down to the 0% bracket.
+type DeductionOptimizationResponse struct {
+	Budget          float64                  `json:"budget" example:"200000"`
+	Spent           float64                  `json:"spent" example:"150000"`
+	Unspent         float64                  `json:"unspent" example:"50000"`
+	Allocation      []DeductionAllocation    `json:"allocation"`
+	TaxBefore       float64                  `json:"taxBefore" example:"29000"`
+	TaxAfter        float64                  `json:"taxAfter" example:"14000"`
+	TaxSaved        float64                  `json:"taxSaved" example:"15000"`
+	TaxSavedPerBaht float64                  `json:"taxSavedPerBaht" example:"0.1"`
+	SaturationPoint float64                  `json:"saturationPoint" example:"150000"`
+	Result          TaxCalculationV2Response `json:"result"`
+}
+
+// DeductionAllocation is how much more to spend on one allowance type, and
+// how much room under its cap is left before spending it.
+type DeductionAllocation struct {
+	AllowanceType string  `json:"allowanceType" example:"donation"`
+	Amount        float64 `json:"amount" example:"100000"`
+	Room          float64 `json:"room" example:"100000"`
+}
+
+// WithholdingRequest describes a year of monthly pay. Months without a
+// payment are paid MonthlySalary and no bonus.
+type WithholdingRequest struct {
+	MonthlySalary *float64         `json:"monthlySalary" example:"50000"`
+	Payments      []MonthlyPayment `json:"payments"`
+	Allowances    []Allowance      `json:"allowances"`
+}
+
+// MonthlyPayment is the pay of one month, 1 for January to 12 for December.
+// Salary overrides MonthlySalary from that month on, e.g. after a raise.
+type MonthlyPayment struct {
+	Month  int      `json:"month" example:"4"`
+	Salary *float64 `json:"salary" example:"55000"`
+	Bonus  float64  `json:"bonus" example:"100000"`
+}
+
+// WithholdingResponse is the PND1 withholding schedule for a year. Over the 12
+// months TotalWithheld adds up to AnnualTax, the tax on the year's pay, unless
+// a pay cut left more withheld, which Result refunds.
+type WithholdingResponse struct {
+	AnnualIncome  float64                  `json:"annualIncome" example:"700000"`
+	AnnualTax     float64                  `json:"annualTax" example:"56000"`
+	TotalWithheld float64                  `json:"totalWithheld" example:"56000"`
+	Schedule      []WithholdingMonth       `json:"schedule"`
+	Result        TaxCalculationV2Response `json:"result"`
+}
+
+// WithholdingMonth is what to withhold in one month. ProjectedAnnualIncome is
+// the pay to date with the month's salary projected over the rest of the
+// year, and BonusWithholding is the part of Withholding due to the bonus.
+type WithholdingMonth struct {
+	Month                 int     `json:"month" example:"4"`
+	Salary                float64 `json:"salary" example:"50000"`
+	Bonus                 float64 `json:"bonus" example:"100000"`
+	ProjectedAnnualIncome float64 `json:"projectedAnnualIncome" example:"700000"`
+	ProjectedAnnualTax    float64 `json:"projectedAnnualTax" example:"56000"`
+	Withholding           float64 `json:"withholding" example:"18416.67"`
+	BonusWithholding      float64 `json:"bonusWithholding" example:"15000"`
+	WithheldToDate        float64 `json:"withheldToDate" example:"28666.68"`
+}
+
+// ProjectionRequest describes five tax years from StartYear, which defaults
+// to the current year. Income grows by GrowthRate a year, e.g. 0.05 for 5%,
+// and WHTRate of each year's income is withheld.
+type ProjectionRequest struct {
+	StartYear      *int             `json:"startYear" example:"2025"`
+	StartingIncome *float64         `json:"startingIncome" example:"600000"`
+	GrowthRate     float64          `json:"growthRate" example:"0.05"`
+	WHTRate        float64          `json:"whtRate" example:"0.05"`
+	Plan           []YearAllowances `json:"plan"`
+}
+
+// YearAllowances are the allowances claimed from Year on, until a later year
+// in the plan replaces them.
+type YearAllowances struct {
+	Year       int         `json:"year" example:"2025"`
+	Allowances []Allowance `json:"allowances"`
+}
+
+// Projection config sources say which deduction configuration a projected
+// year was calculated with.
+const (
+	ProjectionConfigTaxYear = "tax_year"
+	ProjectionConfigLatest  = "latest"
+)
+
+// ProjectionResponse is the tax of every projected year and the totals over
+// all of them.
+type ProjectionResponse struct {
+	Years          []ProjectionYear `json:"years"`
+	TotalIncome    float64          `json:"totalIncome" example:"3315378.75"`
+	TotalTax       float64          `json:"totalTax" example:"56537.87"`
+	TotalTaxRefund float64          `json:"totalTaxRefund" example:"0"`
+}
+
+// ProjectionYear is one year of a projection. ConfigSource is tax_year when
+// the year has its own configuration and latest when MainConfig was used.
+type ProjectionYear struct {
+	Year                int                      `json:"year" example:"2025"`
+	Income              float64                  `json:"income" example:"600000"`
+	ConfigSource        string                   `json:"configSource" enums:"tax_year,latest" example:"latest"`
+	ConfigName          string                   `json:"configName" example:"MainConfig"`
+	ConfigVersion       int64                    `json:"configVersion" example:"1"`
+	WHT                 float64                  `json:"wht" example:"30000"`
+	Tax                 float64                  `json:"tax" example:"5000"`
+	TaxRefund           float64                  `json:"taxRefund" example:"0"`
+	CumulativeTax       float64                  `json:"cumulativeTax" example:"5000"`
+	CumulativeTaxRefund float64                  `json:"cumulativeTaxRefund" example:"0"`
+	Result              TaxCalculationV2Response `json:"result"`
+}
+
+// CSVObjectFormat is one row of an uploaded CSV file. Every allowance column
+// of the row is an allowance, with the column as its type.
 type CSVObjectFormat struct {
-	TotalIncome float64 `csv:"totalIncome"`
-	WHT         float64 `csv:"wht"`
-	Donation    float64 `csv:"donation"`
-	KReceipt    float64 `csv:"k-receipt"`
+	TotalIncome float64     `csv:"totalIncome"`
+	WHT         float64     `csv:"wht"`
+	Allowances  []Allowance `csv:"-"`
 }
 
 type CSVResponseMember struct {
@@ -63,6 +373,48 @@ type CSVResponse struct {
 	Taxes []CSVResponseMember `json:"taxes"`
 }
 
-type ErrorResponse struct {
-	Message string `json:"message"`
+// CSVResponseV2Member adds the tax rates of the row to the v1 response.
+type CSVResponseV2Member struct {
+	CSVResponseMember
+	TaxRates
+}
+
+type CSVResponseV2 struct {
+	Taxes []CSVResponseV2Member `json:"taxes"`
+}
+
+type CSVValidationProblem struct {
+	Row     int    `json:"row" example:"2"`
+	Column  string `json:"column,omitempty" example:"wht"`
+	Value   string `json:"value,omitempty" example:"abc"`
+	Message string `json:"message" example:"invalid number"`
+}
+
+type CSVValidationReport struct {
+	Valid    bool                   `json:"valid"`
+	Columns  []string               `json:"columns"`
+	RowCount int                    `json:"rowCount"`
+	Problems []CSVValidationProblem `json:"problems"`
+}
+
+type BatchTaxCalculationResult struct {
+	Line      int          `json:"line"`
+	Tax       float64      `json:"tax,omitempty"`
+	TaxRefund float64      `json:"taxRefund,omitempty"`
+	TaxLevel  []TaxLevel   `json:"taxLevel,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	ErrorCode string       `json:"errorCode,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type HealthCheckResult struct {
+	Name      string  `json:"name" example:"database"`
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latencyMs" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string              `json:"status" example:"ok"`
+	Checks []HealthCheckResult `json:"checks,omitempty"`
 }
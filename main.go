@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,11 +19,19 @@ import (
 	"github.com/thitiphum-bluesage/assessment-tax/config"
 	_ "github.com/thitiphum-bluesage/assessment-tax/docs"
 	"github.com/thitiphum-bluesage/assessment-tax/infrastructure"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/logging"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/metrics"
 	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/repository"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/tracing"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/endpoints"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/endpoints/controllers"
+	"gorm.io/gorm"
 )
 
+// idempotencyPurgeInterval is how often expired idempotency records are
+// deleted.
+const idempotencyPurgeInterval = time.Hour
+
 // @title KTax API Documentation
 // @description KTax app developed by Thitiphum Chaikarnjanakit as part of the Go KBank Technology Group (KBTG) Bootcamp.
 // @version 1.0
@@ -31,42 +42,92 @@ import (
 // @contact.name Thitiphum Chaikarnjanakit
 // @contact.email chitiphum@gmail.com
 func main() {
+	// Load configuration once and hand it to every component
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Load configuration
-	cfg := config.GetConfig()
-
-	db := infrastructure.InitializeDatabase()
+	logger := logging.New(os.Stdout, logging.ParseLevel(cfg.LogLevel))
+	slog.SetDefault(logger)
 
-	fmt.Println(db)
+	if len(args) > 0 && args[0] == "migrate" {
+		runMigrateCommand(cfg, args[1:])
+		return
+	}
 
-	e := echo.New()
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracesExporter, cfg.ServiceName)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
 
 	// Repository layer
-	taxRepo := repository.NewTaxDeductionConfigRepository(db)
+	var db *gorm.DB
+	var taxConfigRepo repository.TaxDeductionConfigRepositoryInterface
+	var idempotencyRepo repository.IdempotencyRepositoryInterface
+	if cfg.DatabaseDriver == config.DatabaseDriverMemory {
+		slog.Warn("Using in-memory repositories, data will not survive a restart")
+		taxConfigRepo = repository.NewInMemoryTaxDeductionConfigRepository()
+		idempotencyRepo = repository.NewInMemoryIdempotencyRepository()
+	} else {
+		db = infrastructure.InitializeDatabase(cfg)
+
+		if sqlDB, err := db.DB(); err == nil {
+			metrics.RegisterDBStats(sqlDB)
+		}
+
+		taxConfigRepo = repository.NewTaxDeductionConfigRepository(db, cfg.DBQueryTimeout)
+		idempotencyRepo = repository.NewIdempotencyRepository(db, cfg.DBQueryTimeout)
+	}
+	taxRepo := repository.NewCachedTaxDeductionConfigRepository(taxConfigRepo, cfg.ConfigCacheTTL)
+
+	// Keep the cached configuration in sync with changes made by other replicas
+	if cfg.DatabaseDriver == config.DatabaseDriverPostgres {
+		listenerCtx, stopListener := context.WithCancel(context.Background())
+		defer stopListener()
+		go infrastructure.ListenForConfigChanges(listenerCtx, cfg.DatabaseURL, taxRepo.Invalidate)
+	}
+
+	// Delete idempotency records once they can no longer be replayed
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go repository.PurgeExpiredIdempotencyRecords(purgeCtx, idempotencyRepo, cfg.IdempotencyTTL, idempotencyPurgeInterval)
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
 
 	// Service layer
 	adminService := admin.NewAdminService(taxRepo)
-	taxService := tax.NewTaxService(taxRepo)
+	taxService := tax.NewTaxService(taxRepo, tax.WithCSVWorkers(cfg.CSVWorkers))
 
 	// Controller layer
 	adminController := controllers.NewAdminController(adminService)
 	taxController := controllers.NewTaxController(taxService)
+	taxController.SetCSVHeaderAliases(cfg.CSVHeaderAliases)
+	healthController := controllers.NewHealthController(readinessChecks(cfg, db, taxConfigRepo)...)
 
 	// Setup the router with routes
-	endpoints.Router(e, taxController, adminController, cfg)
+	endpoints.Router(e, taxController, adminController, healthController, idempotencyRepo, cfg)
 
 	port := cfg.Port
-	if port == "" {
-		log.Fatal("PORT environment variable not set")
-	}
 
 	// Set up Graceful Shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
+		slog.Info("Starting server", "port", port)
 		if err := e.Start(":" + port); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("shutting down the server: %v", err)
+			logging.Fatal("Shutting down the server", err)
 		}
 	}()
 
@@ -76,6 +137,26 @@ func main() {
 	handleGracefulShutdown(e)
 }
 
+// readinessChecks lists what /readyz verifies for the configured storage
+// backend. The configuration is read through the uncached repository so the
+// check actually reaches the database.
+func readinessChecks(cfg *config.Config, db *gorm.DB, taxConfigRepo repository.TaxDeductionConfigRepositoryInterface) []controllers.ReadinessCheck {
+	var checks []controllers.ReadinessCheck
+	if db != nil {
+		checks = append(checks, controllers.ReadinessCheck{Name: "database", Check: infrastructure.PingDatabase(db)})
+	}
+	if cfg.DatabaseDriver == config.DatabaseDriverPostgres {
+		checks = append(checks, controllers.ReadinessCheck{Name: "migrations", Check: infrastructure.CheckMigrations(db)})
+	}
+	checks = append(checks, controllers.ReadinessCheck{Name: "config", Check: func(ctx context.Context) error {
+		if _, err := taxConfigRepo.GetConfig(ctx); err != nil {
+			return fmt.Errorf("MainConfig deduction configuration is unavailable: %w", err)
+		}
+		return nil
+	}})
+	return checks
+}
+
 func handleGracefulShutdown(e *echo.Echo) {
 	// Create a deadline to wait for.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -83,8 +164,8 @@ func handleGracefulShutdown(e *echo.Echo) {
 
 	// Attempt to shut down the server gracefully
 	if err := e.Shutdown(ctx); err != nil {
-		log.Fatalf("Failed to gracefully shut down the server: %v", err)
+		logging.Fatal("Failed to gracefully shut down the server", err)
 	}
 
-	log.Println("Server shut down gracefully")
+	slog.Info("Server shut down gracefully")
 }
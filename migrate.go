@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/thitiphum-bluesage/assessment-tax/config"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/logging"
+	"github.com/thitiphum-bluesage/assessment-tax/infrastructure/migrations"
+)
+
+const migrateUsage = "usage: migrate up | down | status | to <version>"
+
+// runMigrateCommand handles `migrate <action>` and exits the process.
+func runMigrateCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		logging.Fatal(migrateUsage, nil)
+	}
+
+	if cfg.DatabaseDriver != config.DatabaseDriverPostgres {
+		logging.Fatal(fmt.Sprintf("Migrations only apply to the postgres driver, DATABASE_DRIVER is %q", cfg.DatabaseDriver), nil)
+	}
+	db := infrastructure.OpenDatabase(cfg.DatabaseURL)
+	sqlDB, err := db.DB()
+	if err != nil {
+		logging.Fatal("Failed to get database connection", err)
+	}
+	defer sqlDB.Close()
+
+	migrator, err := migrations.NewMigrator(sqlDB)
+	if err != nil {
+		logging.Fatal("Failed to load migrations", err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			logging.Fatal(migrateUsage, nil)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			logging.Fatal(fmt.Sprintf("Invalid version %q", args[1]), convErr)
+		}
+		err = migrator.To(ctx, version)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	default:
+		logging.Fatal(migrateUsage, nil)
+	}
+	if err != nil {
+		logging.Fatal("Migration failed", err)
+	}
+
+	if args[0] != "status" {
+		current, err := migrator.CurrentVersion(ctx)
+		if err != nil {
+			logging.Fatal("Failed to read schema version", err)
+		}
+		slog.Info("Database schema version", "version", current, "latest", migrator.LatestVersion())
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
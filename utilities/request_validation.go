@@ -3,45 +3,169 @@ package utilities
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 
+	"github.com/thitiphum-bluesage/assessment-tax/domains"
 	"github.com/thitiphum-bluesage/assessment-tax/interfaces/schemas"
+	"golang.org/x/text/message"
 )
 
+// ValidationError reports every problem found in a request. Message keeps the
+// wording of the original single-string errors, and Fields lists each problem
+// with the JSON path of the field it concerns. Both are in English; Localize
+// renders them in another language.
+type ValidationError struct {
+	Message string
+	Fields  []schemas.FieldError
+
+	// messages holds the catalogue key and arguments of each field message,
+	// and joined is set when Message lists them all rather than being the
+	// message of the only field.
+	messages []fieldMessage
+	joined   bool
+}
+
+type fieldMessage struct {
+	key  string
+	args []interface{}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) add(field, code string, value interface{}, key string, args ...interface{}) {
+	e.Fields = append(e.Fields, schemas.FieldError{Field: field, Code: code, Value: value, Message: fmt.Sprintf(key, args...)})
+	e.messages = append(e.messages, fieldMessage{key: key, args: args})
+}
+
+// Localize returns a copy of e with its messages rendered by printer.
+func (e *ValidationError) Localize(printer *message.Printer) *ValidationError {
+	localized := &ValidationError{Fields: make([]schemas.FieldError, len(e.Fields)), messages: e.messages, joined: e.joined}
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		field.Message = printer.Sprintf(e.messages[i].key, e.messages[i].args...)
+		localized.Fields[i] = field
+		messages[i] = field.Message
+	}
+	localized.Message = strings.Join(messages, ", ")
+	if e.joined {
+		localized.Message = printer.Sprintf("validation errors: %s", localized.Message)
+	}
+	return localized
+}
+
+func fieldValidationError(field, code string, value interface{}, key string) *ValidationError {
+	validationErr := &ValidationError{}
+	validationErr.add(field, code, value, key)
+	validationErr.Message = validationErr.Fields[0].Message
+	return validationErr
+}
+
 func ValidateUpdatePersonalDeductionRequest(req *schemas.UpdatePersonalDeductionRequest) error {
 	if req.Amount == nil {
-		return fmt.Errorf("amount is required")
+		return fieldValidationError("amount", schemas.FieldCodeRequired, nil, "amount is required")
 	} else if *req.Amount < 10000 || *req.Amount > 100000 {
-		return fmt.Errorf("amount must be between 10,000 and 100,000")
+		return fieldValidationError("amount", schemas.FieldCodeOutOfRange, *req.Amount, "amount must be between 10,000 and 100,000")
 	}
 	return nil
 }
 
 func ValidateUpdateKReceiptRequest(req *schemas.UpdateKReceiptRequest) error {
 	if req.Amount == nil {
-		return fmt.Errorf("amount for k-receipt is required")
+		return fieldValidationError("amount", schemas.FieldCodeRequired, nil, "amount for k-receipt is required")
 	} else if *req.Amount < 1 || *req.Amount > 100000 {
-		return fmt.Errorf("amount for k-receipt must be between 1 and 100,000")
+		return fieldValidationError("amount", schemas.FieldCodeOutOfRange, *req.Amount, "amount for k-receipt must be between 1 and 100,000")
+	}
+	return nil
+}
+
+func ValidateUpdateInsuranceRequest(req *schemas.UpdateInsuranceRequest) error {
+	if req.Amount == nil {
+		return fieldValidationError("amount", schemas.FieldCodeRequired, nil, "amount for insurance is required")
+	} else if *req.Amount < 1 || *req.Amount > 100000 {
+		return fieldValidationError("amount", schemas.FieldCodeOutOfRange, *req.Amount, "amount for insurance must be between 1 and 100,000")
+	}
+	return nil
+}
+
+func ValidateUpdateRMFRequest(req *schemas.UpdateRMFRequest) error {
+	if req.Amount == nil {
+		return fieldValidationError("amount", schemas.FieldCodeRequired, nil, "amount for rmf is required")
+	} else if *req.Amount < 1 || *req.Amount > 500000 {
+		return fieldValidationError("amount", schemas.FieldCodeOutOfRange, *req.Amount, "amount for rmf must be between 1 and 500,000")
+	}
+	return nil
+}
+
+func ValidateUpdateSSFRequest(req *schemas.UpdateSSFRequest) error {
+	if req.Amount == nil {
+		return fieldValidationError("amount", schemas.FieldCodeRequired, nil, "amount for ssf is required")
+	} else if *req.Amount < 1 || *req.Amount > 200000 {
+		return fieldValidationError("amount", schemas.FieldCodeOutOfRange, *req.Amount, "amount for ssf must be between 1 and 200,000")
 	}
 	return nil
 }
 
+// ValidateTaxYearConfigRequest checks the tax year taken from the path and
+// the limits of req. Limits that are left out are not checked.
+func ValidateTaxYearConfigRequest(year string, req *schemas.TaxYearConfigRequest) error {
+	validationErr := &ValidationError{joined: true}
+	add := validationErr.add
+
+	// Years are passed as strings so the printer does not group their digits.
+	if taxYear, err := strconv.Atoi(year); err != nil {
+		add("year", schemas.FieldCodeInvalidNumber, year, "year must be a whole number")
+	} else if taxYear < MinTaxYear || taxYear > MaxTaxYear {
+		add("year", schemas.FieldCodeOutOfRange, taxYear, "year must be between %s and %s",
+			strconv.Itoa(MinTaxYear), strconv.Itoa(MaxTaxYear))
+	}
+	if req.PersonalDeduction != nil && (*req.PersonalDeduction < 10000 || *req.PersonalDeduction > 100000) {
+		add("personalDeduction", schemas.FieldCodeOutOfRange, *req.PersonalDeduction, "personalDeduction must be between 10,000 and 100,000")
+	}
+	if req.KReceipt != nil && (*req.KReceipt < 1 || *req.KReceipt > 100000) {
+		add("kReceipt", schemas.FieldCodeOutOfRange, *req.KReceipt, "kReceipt must be between 1 and 100,000")
+	}
+	if req.Insurance != nil && (*req.Insurance < 1 || *req.Insurance > 100000) {
+		add("insurance", schemas.FieldCodeOutOfRange, *req.Insurance, "insurance must be between 1 and 100,000")
+	}
+	if req.RMF != nil && (*req.RMF < 1 || *req.RMF > 500000) {
+		add("rmf", schemas.FieldCodeOutOfRange, *req.RMF, "rmf must be between 1 and 500,000")
+	}
+	if req.SSF != nil && (*req.SSF < 1 || *req.SSF > 200000) {
+		add("ssf", schemas.FieldCodeOutOfRange, *req.SSF, "ssf must be between 1 and 200,000")
+	}
+
+	return validationErr.orNil()
+}
+
 func ValidateTaxCalculationRequest(req *schemas.TaxCalculationRequest) error {
-	var errs []string
+	validationErr := &ValidationError{joined: true}
+	validationErr.addTaxCalculationRequest("", req)
+	return validationErr.orNil()
+}
+
+// addTaxCalculationRequest checks one tax calculation request, prefixing every
+// field path with prefix so that requests nested in another can be checked.
+func (e *ValidationError) addTaxCalculationRequest(prefix string, req *schemas.TaxCalculationRequest) {
+	add := func(field, code string, value interface{}, key string, args ...interface{}) {
+		e.add(prefix+field, code, value, key, args...)
+	}
 
 	// Check and dereference pointers for TotalIncome and WHT
 	if req.TotalIncome == nil {
-		errs = append(errs, "TotalIncome is required")
+		add("totalIncome", schemas.FieldCodeRequired, nil, "TotalIncome is required")
 	} else if *req.TotalIncome < 0 {
-		errs = append(errs, "TotalIncome must be non-negative")
+		add("totalIncome", schemas.FieldCodeMustBeNonNegative, *req.TotalIncome, "TotalIncome must be non-negative")
 	}
 
 	if req.WHT == nil {
-		errs = append(errs, "WHT is required")
+		add("wht", schemas.FieldCodeRequired, nil, "WHT is required")
 	} else if *req.WHT < 0 {
-		errs = append(errs, "WHT must be non-negative")
+		add("wht", schemas.FieldCodeMustBeNonNegative, *req.WHT, "WHT must be non-negative")
 	} else if req.TotalIncome != nil && *req.WHT > *req.TotalIncome {
-		errs = append(errs, "WHT cannot be greater than TotalIncome")
+		add("wht", schemas.FieldCodeExceedsTotalIncome, *req.WHT, "WHT cannot be greater than TotalIncome")
 	}
 
 	// Uncomment to not allow blank allowances
@@ -49,66 +173,300 @@ func ValidateTaxCalculationRequest(req *schemas.TaxCalculationRequest) error {
 	// 	errs = append(errs, "At least one allowance must be provided")
 	// }
 
+	e.addAllowances(prefix, req.Allowances)
+}
+
+// ValidateDeductionOptimizationRequest checks the tax calculation request, the
+// budget and the items of a deduction optimization. Items must be allowance
+// types, listed once each.
+func ValidateDeductionOptimizationRequest(req *schemas.DeductionOptimizationRequest) error {
+	validationErr := &ValidationError{joined: true}
+	add := validationErr.add
+	validationErr.addTaxCalculationRequest("", &req.TaxCalculationRequest)
+
+	if req.Budget == nil {
+		add("budget", schemas.FieldCodeRequired, nil, "Budget is required")
+	} else if *req.Budget < 0 {
+		add("budget", schemas.FieldCodeMustBeNonNegative, *req.Budget, "Budget must be non-negative")
+	}
+
+	seen := map[string]bool{}
+	for i, item := range req.Items {
+		path := fmt.Sprintf("items[%d]", i)
+		if !domains.IsAllowanceType(item) {
+			add(path, schemas.FieldCodeInvalidAllowanceType, item, invalidAllowanceTypeMessage, item)
+		} else if seen[item] {
+			add(path, schemas.FieldCodeDuplicateAllowance, item, "%s can be listed only once", item)
+		}
+		seen[item] = true
+	}
+	return validationErr.orNil()
+}
+
+// ValidateWithholdingRequest checks a year of monthly pay. Every month from
+// 1 to 12 can have at most one payment.
+func ValidateWithholdingRequest(req *schemas.WithholdingRequest) error {
+	validationErr := &ValidationError{joined: true}
+	add := validationErr.add
+
+	if req.MonthlySalary == nil {
+		add("monthlySalary", schemas.FieldCodeRequired, nil, "MonthlySalary is required")
+	} else if *req.MonthlySalary < 0 {
+		add("monthlySalary", schemas.FieldCodeMustBeNonNegative, *req.MonthlySalary, "MonthlySalary must be non-negative")
+	}
+
+	months := map[int]bool{}
+	for i, payment := range req.Payments {
+		path := fmt.Sprintf("payments[%d]", i)
+		if payment.Month < 1 || payment.Month > 12 {
+			add(path+".month", schemas.FieldCodeOutOfRange, payment.Month, "Month must be between 1 and 12")
+		} else if months[payment.Month] {
+			add(path+".month", schemas.FieldCodeDuplicateMonth, payment.Month, "Only one payment can be included for month %d", payment.Month)
+		}
+		months[payment.Month] = true
+		if payment.Salary != nil && *payment.Salary < 0 {
+			add(path+".salary", schemas.FieldCodeMustBeNonNegative, *payment.Salary, "Salary must be non-negative")
+		}
+		if payment.Bonus < 0 {
+			add(path+".bonus", schemas.FieldCodeMustBeNonNegative, payment.Bonus, "Bonus must be non-negative")
+		}
+	}
+
+	validationErr.addAllowances("", req.Allowances)
+	return validationErr.orNil()
+}
+
+// MaxScenarioVariants caps how many variants one scenario comparison takes.
+const MaxScenarioVariants = 20
+
+// ValidateScenarioComparisonRequest checks the base and every variant as a
+// tax calculation request. Variants need a name that is unique in the request.
+func ValidateScenarioComparisonRequest(req *schemas.ScenarioComparisonRequest) error {
+	validationErr := &ValidationError{joined: true}
+	add := validationErr.add
+
+	if req.Base == nil {
+		add("base", schemas.FieldCodeRequired, nil, "base is required")
+	} else {
+		validationErr.addTaxCalculationRequest("base.", req.Base)
+	}
+
+	if len(req.Variants) == 0 {
+		add("variants", schemas.FieldCodeRequired, nil, "at least one variant is required")
+	} else if len(req.Variants) > MaxScenarioVariants {
+		add("variants", schemas.FieldCodeOutOfRange, len(req.Variants), "at most %d variants can be compared", MaxScenarioVariants)
+	}
+
+	names := map[string]bool{}
+	for i, variant := range req.Variants {
+		prefix := fmt.Sprintf("variants[%d].", i)
+		if variant.Name == "" {
+			add(prefix+"name", schemas.FieldCodeRequired, nil, "variant name is required")
+		} else if names[variant.Name] {
+			add(prefix+"name", schemas.FieldCodeDuplicateName, variant.Name, "variant name %s is used more than once", variant.Name)
+		}
+		names[variant.Name] = true
+		validationErr.addTaxCalculationRequest(prefix, &variant.TaxCalculationRequest)
+	}
+
+	return validationErr.orNil()
+}
+
+// ValidateGrossUpRequest checks a gross-up request. The rate policy needs a
+// rate below 100%. Callers default a missing WHT policy to full withholding
+// before validating.
+func ValidateGrossUpRequest(req *schemas.GrossUpRequest) error {
+	validationErr := &ValidationError{joined: true}
+	add := validationErr.add
+
+	if req.TargetNetIncome == nil {
+		add("targetNetIncome", schemas.FieldCodeRequired, nil, "TargetNetIncome is required")
+	} else if *req.TargetNetIncome < 0 {
+		add("targetNetIncome", schemas.FieldCodeMustBeNonNegative, *req.TargetNetIncome, "TargetNetIncome must be non-negative")
+	}
+
+	switch req.WHTPolicy {
+	case schemas.WHTPolicyFull, schemas.WHTPolicyNone:
+	case schemas.WHTPolicyRate:
+		if req.WHTRate == nil {
+			add("whtRate", schemas.FieldCodeRequired, nil, "WHTRate is required for the rate policy")
+		} else if *req.WHTRate < 0 || *req.WHTRate >= 1 {
+			add("whtRate", schemas.FieldCodeOutOfRange, *req.WHTRate, "WHTRate must be at least 0 and below 1")
+		}
+	default:
+		add("whtPolicy", schemas.FieldCodeInvalidOption, req.WHTPolicy,
+			"Invalid WHT policy: %s. Allowed policies are 'full', 'none' and 'rate'.", req.WHTPolicy)
+	}
+
+	validationErr.addAllowances("", req.Allowances)
+	return validationErr.orNil()
+}
+
+// ProjectionYears is how many tax years a projection covers.
+const ProjectionYears = 5
+
+// MinTaxYear and MaxTaxYear bound the tax years that can be projected or given
+// their own deduction configuration.
+const (
+	MinTaxYear = 2000
+	MaxTaxYear = 2100
+)
+
+// ValidateProjectionRequest checks a projection request. Every year in the
+// plan must be one of the projected years, listed once. Callers default a
+// missing start year to the current year before validating.
+func ValidateProjectionRequest(req *schemas.ProjectionRequest) error {
+	validationErr := &ValidationError{joined: true}
+	add := validationErr.add
+
+	// Years are passed as strings so the printer does not group their digits.
+	if req.StartYear == nil {
+		add("startYear", schemas.FieldCodeRequired, nil, "StartYear is required")
+	} else if *req.StartYear < MinTaxYear || *req.StartYear > MaxTaxYear {
+		add("startYear", schemas.FieldCodeOutOfRange, *req.StartYear, "StartYear must be between %s and %s",
+			strconv.Itoa(MinTaxYear), strconv.Itoa(MaxTaxYear))
+	}
+	if req.StartingIncome == nil {
+		add("startingIncome", schemas.FieldCodeRequired, nil, "StartingIncome is required")
+	} else if *req.StartingIncome < 0 {
+		add("startingIncome", schemas.FieldCodeMustBeNonNegative, *req.StartingIncome, "StartingIncome must be non-negative")
+	}
+	if req.GrowthRate <= -1 {
+		add("growthRate", schemas.FieldCodeOutOfRange, req.GrowthRate, "GrowthRate must be above -1")
+	}
+	if req.WHTRate < 0 || req.WHTRate >= 1 {
+		add("whtRate", schemas.FieldCodeOutOfRange, req.WHTRate, "WHTRate must be at least 0 and below 1")
+	}
+
+	years := map[int]bool{}
+	for i, plan := range req.Plan {
+		prefix := fmt.Sprintf("plan[%d].", i)
+		if req.StartYear != nil && (plan.Year < *req.StartYear || plan.Year >= *req.StartYear+ProjectionYears) {
+			add(prefix+"year", schemas.FieldCodeOutOfRange, plan.Year, "Year must be between %s and %s",
+				strconv.Itoa(*req.StartYear), strconv.Itoa(*req.StartYear+ProjectionYears-1))
+		} else if years[plan.Year] {
+			add(prefix+"year", schemas.FieldCodeDuplicateYear, plan.Year, "Only one plan can be included for year %s", strconv.Itoa(plan.Year))
+		}
+		years[plan.Year] = true
+		validationErr.addAllowances(prefix, plan.Allowances)
+	}
+
+	return validationErr.orNil()
+}
+
+// invalidAllowanceTypeMessage names every type in domains.AllowanceTypes.
+const invalidAllowanceTypeMessage = "Invalid allowance type: %s. Allowed types are 'donation', 'k-receipt', 'insurance', 'rmf' and 'ssf'."
+
+func (e *ValidationError) addAllowances(prefix string, allowances []schemas.Allowance) {
 	allowanceCounts := map[string]int{}
-	for _, allowance := range req.Allowances {
-		if allowance.AllowanceType != "donation" && allowance.AllowanceType != "k-receipt" {
-			errs = append(errs, fmt.Sprintf("Invalid allowance type: %s. Allowed types are 'donation' and 'k-receipt'.", allowance.AllowanceType))
+	for i, allowance := range allowances {
+		path := fmt.Sprintf("%sallowances[%d]", prefix, i)
+		if !domains.IsAllowanceType(allowance.AllowanceType) {
+			e.add(path+".allowanceType", schemas.FieldCodeInvalidAllowanceType, allowance.AllowanceType,
+				invalidAllowanceTypeMessage, allowance.AllowanceType)
 		}
 		if allowance.Amount < 0 {
-			errs = append(errs, fmt.Sprintf("Amount for %s must be non-negative", allowance.AllowanceType))
+			e.add(path+".amount", schemas.FieldCodeMustBeNonNegative, allowance.Amount,
+				"Amount for %s must be non-negative", allowance.AllowanceType)
 		}
 		allowanceCounts[allowance.AllowanceType]++
 		if allowanceCounts[allowance.AllowanceType] > 1 {
-			errs = append(errs, fmt.Sprintf("Only one %s allowance can be included", allowance.AllowanceType))
+			e.add(path+".allowanceType", schemas.FieldCodeDuplicateAllowance, allowance.AllowanceType,
+				"Only one %s allowance can be included", allowance.AllowanceType)
 		}
 	}
+}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("validation errors: %s", strings.Join(errs, ", "))
+// orNil joins the field messages into Message, or returns nil when no
+// problem was found.
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
 	}
-	return nil
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	e.Message = fmt.Sprintf("validation errors: %s", strings.Join(messages, ", "))
+	return e
 }
 
+// CSVRecordIssue describes one problem found in a CSV tax record. Row is the
+// 1-based position of the record and Column is the CSV column it concerns.
+// Message is a format key for Args, so that it can be translated.
+type CSVRecordIssue struct {
+	Row     int
+	Column  string
+	Code    string
+	Message string
+	Args    []interface{}
+}
+
+// csvAllowanceFields keeps the wording of the messages about the donation and
+// k-receipt columns from before any allowance type could be a column.
+var csvAllowanceFields = map[string]string{"donation": "Donation", "k-receipt": "KReceipt"}
+
 func ValidateCSVTaxRecords(records []schemas.CSVObjectFormat) error {
     var errs []string
 
+    for _, issue := range CollectCSVTaxRecordIssues(records) {
+        errs = append(errs, fmt.Sprintf("Record %d: %s", issue.Row, fmt.Sprintf(issue.Message, issue.Args...)))
+    }
+
+    if len(errs) > 0 {
+        return fmt.Errorf("validation errors: %s", strings.Join(errs, "; "))
+    }
+
+    return nil
+}
+
+// CollectCSVTaxRecordIssues returns every problem ValidateCSVTaxRecords checks
+// for, in the same order, without joining them into a single error.
+func CollectCSVTaxRecordIssues(records []schemas.CSVObjectFormat) []CSVRecordIssue {
+    var issues []CSVRecordIssue
+    add := func(row int, column, code, message string, args ...interface{}) {
+        issues = append(issues, CSVRecordIssue{Row: row, Column: column, Code: code, Message: message, Args: args})
+    }
+    addAllowance := func(row int, allowance schemas.Allowance, code, message string) {
+        if name, ok := csvAllowanceFields[allowance.AllowanceType]; ok {
+            add(row, allowance.AllowanceType, code, name+" "+message)
+        } else {
+            add(row, allowance.AllowanceType, code, "Amount for %s "+message, allowance.AllowanceType)
+        }
+    }
+
     for i, record := range records {
+        row := i + 1
         if record.TotalIncome < 0 {
-            errs = append(errs, fmt.Sprintf("Record %d: TotalIncome must be non-negative", i+1))
+            add(row, "totalincome", schemas.FieldCodeMustBeNonNegative, "TotalIncome must be non-negative")
         }
         if record.WHT < 0 {
-            errs = append(errs, fmt.Sprintf("Record %d: WHT must be non-negative", i+1))
+            add(row, "wht", schemas.FieldCodeMustBeNonNegative, "WHT must be non-negative")
         }
-        if record.Donation < 0 {
-            errs = append(errs, fmt.Sprintf("Record %d: Donation must be non-negative", i+1))
-        }
-        if record.KReceipt < 0 {
-            errs = append(errs, fmt.Sprintf("Record %d: KReceipt must be non-negative", i+1))
+        for _, allowance := range record.Allowances {
+            if allowance.Amount < 0 {
+                addAllowance(row, allowance, schemas.FieldCodeMustBeNonNegative, "must be non-negative")
+            }
         }
         if record.WHT > record.TotalIncome {
-            errs = append(errs, fmt.Sprintf("Record %d: WHT cannot be greater than TotalIncome", i+1))
+            add(row, "wht", schemas.FieldCodeExceedsTotalIncome, "WHT cannot be greater than TotalIncome")
         }
 
         // Add type validation
         if !isValidFloat(record.TotalIncome) {
-            errs = append(errs, fmt.Sprintf("Record %d: TotalIncome must be a valid float", i+1))
+            add(row, "totalincome", schemas.FieldCodeInvalidNumber, "TotalIncome must be a valid float")
         }
         if !isValidFloat(record.WHT) {
-            errs = append(errs, fmt.Sprintf("Record %d: WHT must be a valid float", i+1))
-        }
-        if !isValidFloat(record.Donation) {
-            errs = append(errs, fmt.Sprintf("Record %d: Donation must be a valid float", i+1))
+            add(row, "wht", schemas.FieldCodeInvalidNumber, "WHT must be a valid float")
         }
-        if !isValidFloat(record.KReceipt) {
-            errs = append(errs, fmt.Sprintf("Record %d: KReceipt must be a valid float", i+1))
+        for _, allowance := range record.Allowances {
+            if !isValidFloat(allowance.Amount) {
+                addAllowance(row, allowance, schemas.FieldCodeInvalidNumber, "must be a valid float")
+            }
         }
     }
 
-    if len(errs) > 0 {
-        return fmt.Errorf("validation errors: %s", strings.Join(errs, "; "))
-    }
-
-    return nil
+    return issues
 }
 
 func isValidFloat(value float64) bool {
@@ -90,7 +90,7 @@ func TestValidateTaxCalculationRequest(t *testing.T) {
             TotalIncome: &positiveIncome,
             WHT:         &positiveWHT,
             Allowances:  []schemas.Allowance{{AllowanceType: "invalid_type", Amount: 500}},
-        }, "validation errors: Invalid allowance type: invalid_type. Allowed types are 'donation' and 'k-receipt'."},
+        }, "validation errors: Invalid allowance type: invalid_type. Allowed types are 'donation', 'k-receipt', 'insurance', 'rmf' and 'ssf'."},
         {"Negative Allowance Amount", &schemas.TaxCalculationRequest{
             TotalIncome: &positiveIncome,
             WHT:         &positiveWHT,
@@ -135,6 +135,12 @@ func TestValidateTaxCalculationRequest(t *testing.T) {
 
 
 
+// csvAllowances returns the allowances of a CSV row with donation and
+// k-receipt columns.
+func csvAllowances(donation, kReceipt float64) []schemas.Allowance {
+	return []schemas.Allowance{{AllowanceType: "donation", Amount: donation}, {AllowanceType: "k-receipt", Amount: kReceipt}}
+}
+
 func TestValidateCSVTaxRecords(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -142,37 +148,40 @@ func TestValidateCSVTaxRecords(t *testing.T) {
 		expected string
 	}{
 		{"Valid Records", []schemas.CSVObjectFormat{
-			{TotalIncome: 50000, WHT: 3000, Donation: 500, KReceipt: 200},
-			{TotalIncome: 75000, WHT: 5000, Donation: 1000, KReceipt: 500},
+			{TotalIncome: 50000, WHT: 3000, Allowances: csvAllowances(500, 200)},
+			{TotalIncome: 75000, WHT: 5000, Allowances: csvAllowances(1000, 500)},
 		}, ""},
 		{"Negative TotalIncome", []schemas.CSVObjectFormat{
-			{TotalIncome: -100, WHT: 3000, Donation: 500, KReceipt: 200},
+			{TotalIncome: -100, WHT: 3000, Allowances: csvAllowances(500, 200)},
 		}, "validation errors: Record 1: TotalIncome must be non-negative; Record 1: WHT cannot be greater than TotalIncome"},
 		{"Negative WHT", []schemas.CSVObjectFormat{
-			{TotalIncome: 50000, WHT: -100, Donation: 500, KReceipt: 200},
+			{TotalIncome: 50000, WHT: -100, Allowances: csvAllowances(500, 200)},
 		}, "validation errors: Record 1: WHT must be non-negative"},
 		{"Negative Donation", []schemas.CSVObjectFormat{
-			{TotalIncome: 50000, WHT: 3000, Donation: -500, KReceipt: 200},
+			{TotalIncome: 50000, WHT: 3000, Allowances: csvAllowances(-500, 200)},
 		}, "validation errors: Record 1: Donation must be non-negative"},
 		{"Negative KReceipt", []schemas.CSVObjectFormat{
-			{TotalIncome: 50000, WHT: 3000, Donation: 500, KReceipt: -200},
+			{TotalIncome: 50000, WHT: 3000, Allowances: csvAllowances(500, -200)},
 		}, "validation errors: Record 1: KReceipt must be non-negative"},
 		{"WHT greater than TotalIncome", []schemas.CSVObjectFormat{
-			{TotalIncome: 5000, WHT: 6000, Donation: 500, KReceipt: 200},
+			{TotalIncome: 5000, WHT: 6000, Allowances: csvAllowances(500, 200)},
 		}, "validation errors: Record 1: WHT cannot be greater than TotalIncome"},
 		{"Invalid TotalIncome Type", []schemas.CSVObjectFormat{
-			{TotalIncome: math.NaN(), WHT: 3000, Donation: 500, KReceipt: 200},
+			{TotalIncome: math.NaN(), WHT: 3000, Allowances: csvAllowances(500, 200)},
 		}, "validation errors: Record 1: TotalIncome must be a valid float"},
 		{"Invalid Donation Type", []schemas.CSVObjectFormat{
-			{TotalIncome: 50000, WHT: 3000, Donation: math.NaN(), KReceipt: 200},
+			{TotalIncome: 50000, WHT: 3000, Allowances: csvAllowances(math.NaN(), 200)},
 		}, "validation errors: Record 1: Donation must be a valid float"},
 		{"Multiple Validation Errors", []schemas.CSVObjectFormat{
-			{TotalIncome: -100, WHT: -200, Donation: -300, KReceipt: -400},
+			{TotalIncome: -100, WHT: -200, Allowances: csvAllowances(-300, -400)},
 		}, "validation errors: Record 1: TotalIncome must be non-negative; Record 1: WHT must be non-negative; Record 1: Donation must be non-negative; Record 1: KReceipt must be non-negative"},
+		{"Negative RMF", []schemas.CSVObjectFormat{
+			{TotalIncome: 50000, WHT: 3000, Allowances: []schemas.Allowance{{AllowanceType: "rmf", Amount: -100}}},
+		}, "validation errors: Record 1: Amount for rmf must be non-negative"},
 		{"Multiple Records with Errors", []schemas.CSVObjectFormat{
-			{TotalIncome: 50000, WHT: 3000, Donation: 500, KReceipt: 200},
-			{TotalIncome: -100, WHT: 3000, Donation: 500, KReceipt: 200},
-			{TotalIncome: 60000, WHT: -200, Donation: 1000, KReceipt: 300},
+			{TotalIncome: 50000, WHT: 3000, Allowances: csvAllowances(500, 200)},
+			{TotalIncome: -100, WHT: 3000, Allowances: csvAllowances(500, 200)},
+			{TotalIncome: 60000, WHT: -200, Allowances: csvAllowances(1000, 300)},
 		}, "validation errors: Record 2: TotalIncome must be non-negative; Record 2: WHT cannot be greater than TotalIncome; Record 3: WHT must be non-negative"},
 	}
 
@@ -187,3 +196,251 @@ func TestValidateCSVTaxRecords(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTaxCalculationRequest_FieldErrors(t *testing.T) {
+	income := 500000.0
+	req := &schemas.TaxCalculationRequest{
+		TotalIncome: &income,
+		Allowances: []schemas.Allowance{
+			{AllowanceType: "donation", Amount: 100},
+			{AllowanceType: "donation", Amount: -500},
+			{AllowanceType: "bonus", Amount: 1},
+		},
+	}
+
+	err := ValidateTaxCalculationRequest(req)
+
+	var validationErr *ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "wht", Code: schemas.FieldCodeRequired, Message: "WHT is required"},
+			{Field: "allowances[1].amount", Code: schemas.FieldCodeMustBeNonNegative, Value: -500.0, Message: "Amount for donation must be non-negative"},
+			{Field: "allowances[1].allowanceType", Code: schemas.FieldCodeDuplicateAllowance, Value: "donation", Message: "Only one donation allowance can be included"},
+			{Field: "allowances[2].allowanceType", Code: schemas.FieldCodeInvalidAllowanceType, Value: "bonus", Message: "Invalid allowance type: bonus. Allowed types are 'donation', 'k-receipt', 'insurance', 'rmf' and 'ssf'."},
+		}, validationErr.Fields)
+	}
+}
+
+func TestValidateGrossUpRequest(t *testing.T) {
+	target := 400000.0
+	negative := -1.0
+	rate := 0.03
+	fullRate := 1.0
+
+	tests := []struct {
+		name      string
+		req       schemas.GrossUpRequest
+		policy    string
+		errFields []schemas.FieldError
+	}{
+		{"Full policy", schemas.GrossUpRequest{TargetNetIncome: &target, WHTPolicy: "full"}, schemas.WHTPolicyFull, nil},
+		{"Rate policy", schemas.GrossUpRequest{TargetNetIncome: &target, WHTPolicy: "rate", WHTRate: &rate}, schemas.WHTPolicyRate, nil},
+		{"Missing target", schemas.GrossUpRequest{WHTPolicy: "none"}, schemas.WHTPolicyNone, []schemas.FieldError{
+			{Field: "targetNetIncome", Code: schemas.FieldCodeRequired, Message: "TargetNetIncome is required"},
+		}},
+		{"Negative target and unknown policy", schemas.GrossUpRequest{TargetNetIncome: &negative, WHTPolicy: "half"}, "half", []schemas.FieldError{
+			{Field: "targetNetIncome", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "TargetNetIncome must be non-negative"},
+			{Field: "whtPolicy", Code: schemas.FieldCodeInvalidOption, Value: "half", Message: "Invalid WHT policy: half. Allowed policies are 'full', 'none' and 'rate'."},
+		}},
+		{"Rate policy without rate", schemas.GrossUpRequest{TargetNetIncome: &target, WHTPolicy: "rate"}, schemas.WHTPolicyRate, []schemas.FieldError{
+			{Field: "whtRate", Code: schemas.FieldCodeRequired, Message: "WHTRate is required for the rate policy"},
+		}},
+		{"Rate of 100%", schemas.GrossUpRequest{TargetNetIncome: &target, WHTPolicy: "rate", WHTRate: &fullRate}, schemas.WHTPolicyRate, []schemas.FieldError{
+			{Field: "whtRate", Code: schemas.FieldCodeOutOfRange, Value: 1.0, Message: "WHTRate must be at least 0 and below 1"},
+		}},
+		{"Invalid allowance", schemas.GrossUpRequest{TargetNetIncome: &target, WHTPolicy: "full", Allowances: []schemas.Allowance{{AllowanceType: "bonus"}}}, schemas.WHTPolicyFull, []schemas.FieldError{
+			{Field: "allowances[0].allowanceType", Code: schemas.FieldCodeInvalidAllowanceType, Value: "bonus", Message: "Invalid allowance type: bonus. Allowed types are 'donation', 'k-receipt', 'insurance', 'rmf' and 'ssf'."},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGrossUpRequest(&tt.req)
+			assert.Equal(t, tt.policy, tt.req.WHTPolicy)
+			if tt.errFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			if assert.ErrorAs(t, err, &validationErr) {
+				assert.Equal(t, tt.errFields, validationErr.Fields)
+			}
+		})
+	}
+}
+
+func TestValidateScenarioComparisonRequest(t *testing.T) {
+	income, wht, negative := 500000.0, 0.0, -1.0
+	valid := schemas.TaxCalculationRequest{TotalIncome: &income, WHT: &wht}
+
+	tests := []struct {
+		name      string
+		req       schemas.ScenarioComparisonRequest
+		errFields []schemas.FieldError
+	}{
+		{"Valid", schemas.ScenarioComparisonRequest{Base: &valid, Variants: []schemas.ScenarioVariant{{Name: "a", TaxCalculationRequest: valid}}}, nil},
+		{"Missing base and variants", schemas.ScenarioComparisonRequest{}, []schemas.FieldError{
+			{Field: "base", Code: schemas.FieldCodeRequired, Message: "base is required"},
+			{Field: "variants", Code: schemas.FieldCodeRequired, Message: "at least one variant is required"},
+		}},
+		{"Nested fields are prefixed", schemas.ScenarioComparisonRequest{
+			Base: &schemas.TaxCalculationRequest{TotalIncome: &income},
+			Variants: []schemas.ScenarioVariant{
+				{Name: "a", TaxCalculationRequest: valid},
+				{Name: "a", TaxCalculationRequest: schemas.TaxCalculationRequest{
+					TotalIncome: &income, WHT: &wht, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: negative}},
+				}},
+				{TaxCalculationRequest: valid},
+			},
+		}, []schemas.FieldError{
+			{Field: "base.wht", Code: schemas.FieldCodeRequired, Message: "WHT is required"},
+			{Field: "variants[1].name", Code: schemas.FieldCodeDuplicateName, Value: "a", Message: "variant name a is used more than once"},
+			{Field: "variants[1].allowances[0].amount", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "Amount for donation must be non-negative"},
+			{Field: "variants[2].name", Code: schemas.FieldCodeRequired, Message: "variant name is required"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateScenarioComparisonRequest(&tt.req)
+			if tt.errFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			if assert.ErrorAs(t, err, &validationErr) {
+				assert.Equal(t, tt.errFields, validationErr.Fields)
+			}
+		})
+	}
+}
+
+func TestValidateDeductionOptimizationRequest(t *testing.T) {
+	income, wht, budget, negative := 500000.0, 0.0, 100000.0, -1.0
+
+	assert.NoError(t, ValidateDeductionOptimizationRequest(&schemas.DeductionOptimizationRequest{
+		TaxCalculationRequest: schemas.TaxCalculationRequest{TotalIncome: &income, WHT: &wht},
+		Budget:                &budget,
+	}))
+
+	err := ValidateDeductionOptimizationRequest(&schemas.DeductionOptimizationRequest{
+		TaxCalculationRequest: schemas.TaxCalculationRequest{TotalIncome: &income},
+		Budget:                &negative,
+		Items:                 []string{"donation", "insurance", "rmf", "ssf", "car", "donation"},
+	})
+	var validationErr *ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "wht", Code: schemas.FieldCodeRequired, Message: "WHT is required"},
+			{Field: "budget", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "Budget must be non-negative"},
+			{Field: "items[4]", Code: schemas.FieldCodeInvalidAllowanceType, Value: "car", Message: "Invalid allowance type: car. Allowed types are 'donation', 'k-receipt', 'insurance', 'rmf' and 'ssf'."},
+			{Field: "items[5]", Code: schemas.FieldCodeDuplicateAllowance, Value: "donation", Message: "donation can be listed only once"},
+		}, validationErr.Fields)
+	}
+}
+
+func TestValidateWithholdingRequest(t *testing.T) {
+	salary, negative := 50000.0, -1.0
+
+	assert.NoError(t, ValidateWithholdingRequest(&schemas.WithholdingRequest{
+		MonthlySalary: &salary,
+		Payments:      []schemas.MonthlyPayment{{Month: 4, Bonus: 100000}, {Month: 7, Salary: &salary}},
+	}))
+
+	err := ValidateWithholdingRequest(&schemas.WithholdingRequest{
+		Payments: []schemas.MonthlyPayment{{Month: 13}, {Month: 4, Salary: &negative}, {Month: 4, Bonus: -1}},
+	})
+	var validationErr *ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "monthlySalary", Code: schemas.FieldCodeRequired, Message: "MonthlySalary is required"},
+			{Field: "payments[0].month", Code: schemas.FieldCodeOutOfRange, Value: 13, Message: "Month must be between 1 and 12"},
+			{Field: "payments[1].salary", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "Salary must be non-negative"},
+			{Field: "payments[2].month", Code: schemas.FieldCodeDuplicateMonth, Value: 4, Message: "Only one payment can be included for month 4"},
+			{Field: "payments[2].bonus", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "Bonus must be non-negative"},
+		}, validationErr.Fields)
+	}
+}
+
+func TestValidateProjectionRequest(t *testing.T) {
+	income := 600000.0
+
+	startYear := 2026
+	assert.NoError(t, ValidateProjectionRequest(&schemas.ProjectionRequest{
+		StartYear:      &startYear,
+		StartingIncome: &income,
+		GrowthRate:     0.05,
+		Plan:           []schemas.YearAllowances{{Year: 2026}, {Year: 2030}},
+	}))
+
+	req := &schemas.ProjectionRequest{StartingIncome: &income, Plan: []schemas.YearAllowances{{Year: 1999}}}
+	err := ValidateProjectionRequest(req)
+	var validationErr *ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "startYear", Code: schemas.FieldCodeRequired, Message: "StartYear is required"},
+		}, validationErr.Fields)
+	}
+	assert.Nil(t, req.StartYear, "the start year is not defaulted")
+
+	startYear = 2025
+	err = ValidateProjectionRequest(&schemas.ProjectionRequest{
+		StartYear:  &startYear,
+		GrowthRate: -1,
+		WHTRate:    1,
+		Plan: []schemas.YearAllowances{
+			{Year: 2030},
+			{Year: 2027},
+			{Year: 2027, Allowances: []schemas.Allowance{{AllowanceType: "donation", Amount: -1}}},
+		},
+	})
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "startingIncome", Code: schemas.FieldCodeRequired, Message: "StartingIncome is required"},
+			{Field: "growthRate", Code: schemas.FieldCodeOutOfRange, Value: -1.0, Message: "GrowthRate must be above -1"},
+			{Field: "whtRate", Code: schemas.FieldCodeOutOfRange, Value: 1.0, Message: "WHTRate must be at least 0 and below 1"},
+			{Field: "plan[0].year", Code: schemas.FieldCodeOutOfRange, Value: 2030, Message: "Year must be between 2025 and 2029"},
+			{Field: "plan[2].year", Code: schemas.FieldCodeDuplicateYear, Value: 2027, Message: "Only one plan can be included for year 2027"},
+			{Field: "plan[2].allowances[0].amount", Code: schemas.FieldCodeMustBeNonNegative, Value: -1.0, Message: "Amount for donation must be non-negative"},
+		}, validationErr.Fields)
+	}
+
+	startYear = 20250
+	err = ValidateProjectionRequest(&schemas.ProjectionRequest{StartYear: &startYear, StartingIncome: &income})
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "startYear", Code: schemas.FieldCodeOutOfRange, Value: 20250, Message: "StartYear must be between 2000 and 2100"},
+		}, validationErr.Fields)
+	}
+}
+
+func TestValidateTaxYearConfigRequest(t *testing.T) {
+	personalDeduction, rmf := 70000.0, 400000.0
+	assert.NoError(t, ValidateTaxYearConfigRequest("2025", &schemas.TaxYearConfigRequest{}))
+	assert.NoError(t, ValidateTaxYearConfigRequest("2025", &schemas.TaxYearConfigRequest{PersonalDeduction: &personalDeduction, RMF: &rmf}))
+
+	personalDeduction, kReceipt, insurance, ssf := 9999.0, 0.0, 100001.0, 200001.0
+	err := ValidateTaxYearConfigRequest("1999", &schemas.TaxYearConfigRequest{
+		PersonalDeduction: &personalDeduction,
+		KReceipt:          &kReceipt,
+		Insurance:         &insurance,
+		SSF:               &ssf,
+	})
+	var validationErr *ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "year", Code: schemas.FieldCodeOutOfRange, Value: 1999, Message: "year must be between 2000 and 2100"},
+			{Field: "personalDeduction", Code: schemas.FieldCodeOutOfRange, Value: 9999.0, Message: "personalDeduction must be between 10,000 and 100,000"},
+			{Field: "kReceipt", Code: schemas.FieldCodeOutOfRange, Value: 0.0, Message: "kReceipt must be between 1 and 100,000"},
+			{Field: "insurance", Code: schemas.FieldCodeOutOfRange, Value: 100001.0, Message: "insurance must be between 1 and 100,000"},
+			{Field: "ssf", Code: schemas.FieldCodeOutOfRange, Value: 200001.0, Message: "ssf must be between 1 and 200,000"},
+		}, validationErr.Fields)
+	}
+
+	err = ValidateTaxYearConfigRequest("next", &schemas.TaxYearConfigRequest{})
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, []schemas.FieldError{
+			{Field: "year", Code: schemas.FieldCodeInvalidNumber, Value: "next", Message: "year must be a whole number"},
+		}, validationErr.Fields)
+	}
+}